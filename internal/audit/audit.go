@@ -0,0 +1,82 @@
+// Package audit records who changed what: before/after column diffs of the entities written through
+// a Repository, stored in the gormr_audit table.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// Actions recorded in the audit trail.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Auditable is implemented by models opting into the audit trail.
+type Auditable interface {
+	// AuditIgnore returns the columns left out of the recorded diffs (e.g. secrets or updated_at)
+	AuditIgnore() []string
+}
+
+// Change is the value of a column before and after an operation (nil when absent).
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Entry is a change to an entity recorded in the gormr_audit table.
+type Entry struct {
+	ID uint64 `gorm:"primaryKey"`
+	// Table of the entity
+	EntityType string `gorm:"size:191;not null;index:idx_gormr_audit_entity,priority:1"`
+	// Primary key of the entity, formatted with fmt.Sprint
+	EntityID string `gorm:"size:191;not null;index:idx_gormr_audit_entity,priority:2"`
+	Action   string `gorm:"size:16;not null"`
+	// Actor carried by the context of the operation (see repository.WithActor), empty if none
+	Actor string `gorm:"size:191"`
+	// Tenant of the operation, set and filtered on by the tenancy plugin when enabled
+	TenantID  string            `gorm:"size:191;index" gormr:"tenant"`
+	Changes   map[string]Change `gorm:"serializer:json"`
+	CreatedAt time.Time
+}
+
+// TableName returns the table storing the audit trail.
+func (Entry) TableName() string {
+	return "gormr_audit"
+}
+
+// Auditor records the audit trail of a Repository and reads it back.
+type Auditor struct {
+	repo *repository.Repository
+}
+
+// New creates an Auditor storing the audit trail through repo.
+func New(repo *repository.Repository) *Auditor {
+	return &Auditor{repo: repo}
+}
+
+// Migrate creates or updates the gormr_audit table.
+func (a *Auditor) Migrate(ctx context.Context) error {
+	return a.repo.AutoMigrate(ctx, &Entry{})
+}
+
+// History returns the audit trail of the entity of model with primary key id, oldest first. With
+// tenancy enabled, only the entries recorded by operations of the tenant in ctx are returned.
+func (a *Auditor) History(ctx context.Context, model any, id any) ([]Entry, error) {
+	s, err := a.repo.Schema(model)
+	if err != nil {
+		return nil, err
+	}
+	spec := repository.NewSpec(&Entry{}).
+		Eq("entity_type", s.Table).
+		Eq("entity_id", fmt.Sprint(id)).
+		OrderBy("id")
+	var entries []Entry
+	err = a.repo.Find(ctx, spec, &entries)
+	return entries, err
+}
@@ -0,0 +1,251 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/encryption"
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+	"github.com/alejandro-sotelo/gormr/internal/tenancy"
+)
+
+type Account struct {
+	ID       uint `gorm:"primaryKey"`
+	Owner    string
+	Balance  int
+	Password string
+	Deleted  gorm.DeletedAt
+}
+
+func (Account) AuditIgnore() []string {
+	return []string{"password"}
+}
+
+type Note struct {
+	ID   uint `gorm:"primaryKey"`
+	Text string
+}
+
+func setupAuditor(t *testing.T) (*repository.Repository, *Auditor) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&Account{}, &Note{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := repository.New(db)
+	a := New(repo)
+	if err := a.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate audit: %v", err)
+	}
+	repo.Use(a.Middleware())
+	return repo, a
+}
+
+func history(t *testing.T, a *Auditor, id any) []Entry {
+	t.Helper()
+	entries, err := a.History(context.Background(), &Account{}, id)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	return entries
+}
+
+func TestAuditor_RecordsLifecycle(t *testing.T) {
+	repo, a := setupAuditor(t)
+	ctx := repository.WithActor(context.Background(), "alice")
+
+	acc := Account{Owner: "Bob", Balance: 10, Password: "secret"}
+	if err := repo.Create(ctx, &acc); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	acc.Balance = 25
+	acc.Password = "changed"
+	if err := repo.Update(ctx, &acc); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := repo.UpdateMap(ctx, &acc, map[string]any{"owner": "Robert"}); err != nil {
+		t.Fatalf("UpdateMap failed: %v", err)
+	}
+	if err := repo.Delete(repository.WithActor(context.Background(), "carol"), &acc); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	entries := history(t, a, acc.ID)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %+v", entries)
+	}
+	created := entries[0]
+	if created.Action != ActionCreate || created.Actor != "alice" || created.Changes["owner"].New != "Bob" {
+		t.Errorf("unexpected create entry %+v", created)
+	}
+	if _, ok := created.Changes["password"]; ok {
+		t.Error("expected ignored column left out of the diff")
+	}
+	balance := entries[1].Changes["balance"]
+	if entries[1].Action != ActionUpdate || balance.Old != float64(10) || balance.New != float64(25) {
+		t.Errorf("unexpected update entry %+v", entries[1])
+	}
+	if _, ok := entries[1].Changes["owner"]; ok {
+		t.Error("expected unchanged columns left out of the diff")
+	}
+	if entries[2].Changes["owner"].New != "Robert" {
+		t.Errorf("unexpected UpdateMap entry %+v", entries[2])
+	}
+	if entries[3].Action != ActionDelete || entries[3].Actor != "carol" || entries[3].Changes["owner"].Old != "Robert" {
+		t.Errorf("unexpected delete entry %+v", entries[3])
+	}
+}
+
+func TestAuditor_SkipsUnchangedAndUnaudited(t *testing.T) {
+	repo, a := setupAuditor(t)
+	ctx := context.Background()
+
+	acc := Account{Owner: "Bob"}
+	if err := repo.Create(ctx, &acc); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.Update(ctx, &acc); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if n := len(history(t, a, acc.ID)); n != 1 {
+		t.Errorf("expected a no-op update not to be recorded, got %d entries", n)
+	}
+
+	if err := repo.Create(ctx, &Note{Text: "hello"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	n, _ := repo.Count(ctx, repository.NewSpec(&Entry{}))
+	if n != 1 {
+		t.Errorf("expected models not implementing Auditable to be ignored, got %d entries", n)
+	}
+}
+
+func TestAuditor_BatchAndDeleteByID(t *testing.T) {
+	repo, a := setupAuditor(t)
+	ctx := context.Background()
+
+	accounts := []Account{{Owner: "Ann"}, {Owner: "Ben"}}
+	if err := repo.CreateBatch(ctx, accounts, 10); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := repo.DeleteByID(ctx, &Account{}, accounts[1].ID); err != nil {
+		t.Fatalf("DeleteByID failed: %v", err)
+	}
+
+	if entries := history(t, a, accounts[0].ID); len(entries) != 1 || entries[0].Changes["owner"].New != "Ann" {
+		t.Errorf("unexpected history for the first account %+v", entries)
+	}
+	entries := history(t, a, accounts[1].ID)
+	if len(entries) != 2 || entries[1].Action != ActionDelete || entries[1].Changes["owner"].Old != "Ben" {
+		t.Errorf("unexpected history for the deleted account %+v", entries)
+	}
+}
+
+func TestAuditor_RollsBackWithOperation(t *testing.T) {
+	repo, a := setupAuditor(t)
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+
+	var acc Account
+	err := repo.Transaction(ctx, func(txRepo *repository.Repository) error {
+		acc = Account{Owner: "Bob"}
+		if err := txRepo.Create(ctx, &acc); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if entries := history(t, a, acc.ID); len(entries) != 0 {
+		t.Errorf("expected the audit entry rolled back, got %+v", entries)
+	}
+}
+
+type Patient struct {
+	ID  uint   `gorm:"primaryKey"`
+	SSN string `gormr:"encrypted"`
+}
+
+func (Patient) AuditIgnore() []string {
+	return nil
+}
+
+func TestAuditor_KeepsCiphertext(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	keys, err := encryption.NewStaticKeys("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatalf("NewStaticKeys failed: %v", err)
+	}
+	e, err := encryption.New(encryption.Config{Keys: keys})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := db.Use(e); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&Patient{}, &Entry{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := repository.New(db)
+	a := New(repo)
+	repo.Use(a.Middleware(), e.Middleware())
+	ctx := context.Background()
+
+	p := &Patient{SSN: "123-45-6789"}
+	if err := repo.Create(ctx, p); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	entries, err := a.History(ctx, &Patient{}, p.ID)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %+v (%v)", entries, err)
+	}
+	ssn := fmt.Sprint(entries[0].Changes["ssn"].New)
+	if !strings.HasPrefix(ssn, "enc:k1:") {
+		t.Errorf("expected the audit trail to hold the ciphertext, got %q", ssn)
+	}
+}
+
+func TestAuditor_Tenancy(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.Use(tenancy.New(tenancy.Config{})); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&Account{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := repository.New(db)
+	a := New(repo)
+	if err := a.Migrate(tenancy.WithoutTenant(context.Background())); err != nil {
+		t.Fatalf("failed to migrate audit: %v", err)
+	}
+	repo.Use(a.Middleware())
+	acme := tenancy.WithTenant(context.Background(), "acme")
+	globex := tenancy.WithTenant(context.Background(), "globex")
+
+	acc := Account{Owner: "Bob"}
+	if err := repo.Create(acme, &acc); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if entries, err := a.History(acme, &Account{}, acc.ID); err != nil || len(entries) != 1 || entries[0].TenantID != "acme" {
+		t.Errorf("expected the entry of acme, got %+v (%v)", entries, err)
+	}
+	if entries, err := a.History(globex, &Account{}, acc.ID); err != nil || len(entries) != 0 {
+		t.Errorf("expected another tenant not to read the history, got %+v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// actions maps the audited operations to the action they record.
+var actions = map[string]string{
+	repository.OpCreate:       ActionCreate,
+	repository.OpCreateBatch:  ActionCreate,
+	repository.OpUpdate:       ActionUpdate,
+	repository.OpUpdateFields: ActionUpdate,
+	repository.OpUpdateMap:    ActionUpdate,
+	repository.OpDelete:       ActionDelete,
+	repository.OpSoftDelete:   ActionDelete,
+	repository.OpForceDelete:  ActionDelete,
+	repository.OpDeleteByID:   ActionDelete,
+}
+
+var auditableType = reflect.TypeFor[Auditable]()
+
+// Middleware returns the repository middleware recording the audit trail of Auditable models.
+//
+// Create, CreateBatch, Update, UpdateFields, UpdateMap, Delete, SoftDelete, ForceDelete and
+// DeleteByID are audited: the rows are read before and after the operation and the column diffs
+// are written in the operation's transaction (one is started if none is active), so the trail
+// is committed or rolled back together with the change. Updates leaving every column unchanged
+// are not recorded. Bulk operations (UpdateWhere, DeleteWhere, Upsert, Purge) are not audited.
+func (a *Auditor) Middleware() repository.Middleware {
+	return func(next repository.Handler) repository.Handler {
+		return func(ctx context.Context, op *repository.Operation) (any, error) {
+			action, ok := actions[op.Name]
+			if !ok || !isAuditable(op.Model) {
+				return next(ctx, op)
+			}
+			var res any
+			err := op.Repo.RunInTransaction(ctx, func(ctx context.Context) error {
+				rec, err := newRecorder(ctx, op, action)
+				if err != nil {
+					return err
+				}
+				if res, err = next(ctx, op); err != nil {
+					return err
+				}
+				return rec.record(ctx)
+			})
+			return res, err
+		}
+	}
+}
+
+// isAuditable reports whether model (an entity, pointer or slice of them) implements Auditable.
+func isAuditable(model any) bool {
+	t := reflect.TypeOf(model)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		if t.Implements(auditableType) {
+			return true
+		}
+		t = t.Elem()
+	}
+	return t != nil && (t.Implements(auditableType) || reflect.PointerTo(t).Implements(auditableType))
+}
+
+// target is an audited row and its columns before the operation.
+type target struct {
+	entity any
+	id     any
+	before map[string]any
+}
+
+// recorder captures the audited rows of an operation and writes their diffs.
+type recorder struct {
+	repo    *repository.Repository
+	action  string
+	schema  *schema.Schema
+	pk      *schema.Field
+	ignore  []string
+	targets []target
+}
+
+func newRecorder(ctx context.Context, op *repository.Operation, action string) (*recorder, error) {
+	s, err := op.Repo.Schema(op.Model)
+	if err != nil {
+		return nil, err
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("gormr: audited model %s has no single primary key", s.Name)
+	}
+	rec := &recorder{repo: op.Repo, action: action, schema: s, pk: s.PrioritizedPrimaryField}
+	if a, ok := reflect.New(s.ModelType).Interface().(Auditable); ok {
+		rec.ignore = a.AuditIgnore()
+	}
+
+	if op.Name == repository.OpDeleteByID {
+		rec.targets = []target{{id: op.Args[1]}}
+	} else {
+		for _, e := range entities(op.Args[0]) {
+			rec.targets = append(rec.targets, target{entity: e})
+		}
+	}
+	if action == ActionCreate {
+		return rec, nil
+	}
+	for i := range rec.targets {
+		t := &rec.targets[i]
+		if t.entity != nil {
+			t.id = rec.id(ctx, t.entity)
+		}
+		if t.before, err = rec.snapshot(ctx, t.id); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+// record writes the diffs of the targets after the operation ran.
+func (r *recorder) record(ctx context.Context) error {
+	actor, _ := repository.ActorFromContext(ctx)
+	var entries []*Entry
+	for _, t := range r.targets {
+		// Inserts assign the primary key
+		if t.entity != nil {
+			t.id = r.id(ctx, t.entity)
+		}
+		var after map[string]any
+		if r.action != ActionDelete {
+			var err error
+			if after, err = r.snapshot(ctx, t.id); err != nil {
+				return err
+			}
+		}
+		changes := r.diff(t.before, after)
+		if len(changes) == 0 {
+			continue
+		}
+		entries = append(entries, &Entry{
+			EntityType: r.schema.Table,
+			EntityID:   fmt.Sprint(t.id),
+			Action:     r.action,
+			Actor:      actor,
+			Changes:    changes,
+		})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.repo.Create(ctx, entries)
+}
+
+// id returns the primary key of entity.
+func (r *recorder) id(ctx context.Context, entity any) any {
+	v, _ := r.pk.ValueOf(ctx, reflect.ValueOf(entity))
+	return v
+}
+
+// snapshot reads the columns of the row with primary key id, or nil if there is none. Values are
+// read as stored, so encrypted columns stay encrypted in the audit trail.
+func (r *recorder) snapshot(ctx context.Context, id any) (map[string]any, error) {
+	row := map[string]any{}
+	model := reflect.New(r.schema.ModelType).Interface()
+	if err := r.repo.GetByID(ctx, model, id, &row, repository.WithTrashed(), repository.RawValues()); err != nil {
+		return nil, err
+	}
+	if len(row) == 0 {
+		return nil, nil
+	}
+	return row, nil
+}
+
+// diff returns the columns whose value differs between before and after.
+func (r *recorder) diff(before, after map[string]any) map[string]Change {
+	changes := map[string]Change{}
+	for _, f := range r.schema.DBNames {
+		if slices.Contains(r.ignore, f) {
+			continue
+		}
+		old, cur := before[f], after[f]
+		if reflect.DeepEqual(old, cur) {
+			continue
+		}
+		changes[f] = Change{Old: old, New: cur}
+	}
+	return changes
+}
+
+// entities returns the entities of arg, an entity or a slice (or pointer to a slice) of them,
+// as pointers so their primary key can be read after an insert.
+func entities(arg any) []any {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Slice {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return []any{arg}
+	}
+	out := make([]any, v.Len())
+	for i := range out {
+		e := v.Index(i)
+		if e.Kind() != reflect.Pointer {
+			e = e.Addr()
+		}
+		out[i] = e.Interface()
+	}
+	return out
+}
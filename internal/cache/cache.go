@@ -0,0 +1,388 @@
+// Package cache is a read-through cache for Repository.GetByID, as a repository middleware, with
+// invalidation on the writes made through the same Repository.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm/schema"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// Cache stores byte values with a time to live. Its methods map to Redis' GET, SET with EX/PX
+// and DEL, so a Redis client is easily adapted.
+type Cache interface {
+	// Get returns the value stored under key, reporting false when there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl (forever if ttl <= 0).
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Config configures the Cacher. Zero values select the defaults.
+type Config struct {
+	// Store of the entries (default NewLRU(10000))
+	Cache Cache
+	// Time to live of the entries (default 5m)
+	TTL time.Duration
+	// Prefix of the keys (default "gormr:")
+	Prefix string
+	// Cached models (default: every model). Entities are cached encoded with encoding/gob as
+	// GetByID returns them, so models with encrypted fields should not be cached in a shared
+	// store.
+	Models []any
+	// Scope partitions the entries per context, e.g. per tenant. Reads with an empty scope skip
+	// the cache and writes with an empty scope invalidate the whole table.
+	Scope func(ctx context.Context) string
+	// OnError is called with the errors of the Cache, which otherwise fall back to the database
+	OnError func(err error)
+}
+
+// Cacher caches the entities read by GetByID.
+type Cacher struct {
+	cfg   Config
+	group singleflight.Group
+
+	tablesOnce sync.Once
+	tables     map[string]bool
+	tablesErr  error
+
+	// fills are the keys being filled, marked stale when invalidated meanwhile
+	fillsMu sync.Mutex
+	fills   map[string]*fill
+}
+
+// fill is a cache fill in progress.
+type fill struct {
+	stale bool
+}
+
+// New creates a Cacher.
+func New(cfg Config) *Cacher {
+	if cfg.Cache == nil {
+		cfg.Cache = NewLRU(10000)
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "gormr:"
+	}
+	return &Cacher{cfg: cfg, fills: map[string]*fill{}}
+}
+
+// Middleware returns the repository middleware caching GetByID.
+//
+// GetByID calls into the model type (not DTOs) without options, outside transactions, read the
+// cache first; on a miss a single call per key queries the database (concurrent calls wait for
+// it) and fills the cache. Records not found are not cached. Update, UpdateFields, UpdateMap,
+// Upsert, Delete, DeleteByID, SoftDelete, Restore and ForceDelete invalidate their entities,
+// UpdateWhere, DeleteWhere and Purge the whole table, once their transaction commits.
+//
+// A fill is dropped when its entry is invalidated while the row is read, so the row read before a
+// write is not stored after the write invalidated it. This holds for the writes made through the
+// same Cacher: an entry invalidated by another process during a fill may still be stored stale
+// until it expires, like the rows written bypassing the Repository.
+func (c *Cacher) Middleware() repository.Middleware {
+	return func(next repository.Handler) repository.Handler {
+		return func(ctx context.Context, op *repository.Operation) (any, error) {
+			s, ok := c.schema(op)
+			if !ok {
+				return next(ctx, op)
+			}
+			switch op.Name {
+			case repository.OpGetByID:
+				return c.get(ctx, op, s, next)
+			case repository.OpUpdate, repository.OpUpdateFields, repository.OpUpdateMap, repository.OpUpsert,
+				repository.OpDelete, repository.OpSoftDelete, repository.OpRestore, repository.OpForceDelete,
+				repository.OpDeleteByID, repository.OpUpdateWhere, repository.OpDeleteWhere, repository.OpPurge:
+				return c.write(ctx, op, s, next)
+			}
+			return next(ctx, op)
+		}
+	}
+}
+
+// Invalidate removes the entries of model with the given primary keys, or of the whole table
+// of model when no id is given, e.g. after writing to the table without the Repository.
+func (c *Cacher) Invalidate(ctx context.Context, repo *repository.Repository, model any, ids ...any) error {
+	s, err := repo.Schema(model)
+	if err != nil {
+		return err
+	}
+	return c.invalidate(ctx, s.Table, ids)
+}
+
+// schema returns the schema of the model of op if it is cached.
+func (c *Cacher) schema(op *repository.Operation) (*schema.Schema, bool) {
+	c.tablesOnce.Do(func() {
+		if len(c.cfg.Models) == 0 {
+			return
+		}
+		c.tables = map[string]bool{}
+		for _, m := range c.cfg.Models {
+			s, err := op.Repo.Schema(m)
+			if err != nil {
+				c.tablesErr = err
+				return
+			}
+			c.tables[s.Table] = true
+		}
+	})
+	if c.tablesErr != nil || op.Model == nil {
+		return nil, false
+	}
+	s, err := op.Repo.Schema(op.Model)
+	if err != nil || (c.tables != nil && !c.tables[s.Table]) {
+		return nil, false
+	}
+	return s, true
+}
+
+// get serves GetByID from the cache, filling it on a miss.
+func (c *Cacher) get(ctx context.Context, op *repository.Operation, s *schema.Schema, next repository.Handler) (any, error) {
+	id, out := op.Args[1], op.Args[2]
+	if opts, _ := op.Args[3].([]repository.QueryOption); len(opts) > 0 || op.Repo.InTransaction(ctx) {
+		return next(ctx, op)
+	}
+	// Entries hold the model: other destinations would share their keys
+	if rv := reflect.ValueOf(out); rv.Kind() != reflect.Pointer || rv.Type().Elem() != s.ModelType {
+		return next(ctx, op)
+	}
+	scope, ok := c.scope(ctx)
+	if !ok {
+		return next(ctx, op)
+	}
+	version, err := c.version(ctx, s.Table)
+	if err != nil {
+		c.fail(err)
+		return next(ctx, op)
+	}
+	key := c.entryKey(s.Table, scope, version, id)
+
+	data, found, err := c.cfg.Cache.Get(ctx, key)
+	if err != nil {
+		c.fail(err)
+	}
+	if found && decode(data, out) == nil {
+		return nil, nil
+	}
+
+	leader := false
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		leader = true
+		f := c.startFill(key)
+		defer c.endFill(key)
+		if _, err := next(ctx, op); err != nil {
+			return nil, err
+		}
+		if reflect.Indirect(reflect.ValueOf(out)).IsZero() {
+			// Not found
+			return nil, nil
+		}
+		data, err := encode(out)
+		if err != nil {
+			c.fail(err)
+			return nil, nil
+		}
+		if c.stale(f) {
+			return data, nil
+		}
+		if err := c.cfg.Cache.Set(ctx, key, data, c.cfg.TTL); err != nil {
+			c.fail(err)
+		}
+		return data, nil
+	})
+	if err != nil || leader || v == nil {
+		return nil, err
+	}
+	return nil, decode(v.([]byte), out)
+}
+
+// startFill registers the fill of key.
+func (c *Cacher) startFill(key string) *fill {
+	f := &fill{}
+	c.fillsMu.Lock()
+	c.fills[key] = f
+	c.fillsMu.Unlock()
+	return f
+}
+
+func (c *Cacher) endFill(key string) {
+	c.fillsMu.Lock()
+	delete(c.fills, key)
+	c.fillsMu.Unlock()
+}
+
+// stale reports whether the entry filled by f was invalidated since its read started. Table
+// invalidations need no check: they change the version in the keys.
+func (c *Cacher) stale(f *fill) bool {
+	c.fillsMu.Lock()
+	defer c.fillsMu.Unlock()
+	return f.stale
+}
+
+// write runs a write and, once committed, invalidates the entries of the written entities, or
+// of the whole table for bulk writes.
+func (c *Cacher) write(ctx context.Context, op *repository.Operation, s *schema.Schema, next repository.Handler) (any, error) {
+	res, err := next(ctx, op)
+	if err != nil {
+		return res, err
+	}
+	ids := written(ctx, op, s)
+	ctx = context.WithoutCancel(ctx)
+	op.Repo.AfterCommit(ctx, func() {
+		if err := c.invalidate(ctx, s.Table, ids); err != nil {
+			c.fail(err)
+		}
+	})
+	return res, err
+}
+
+// invalidate removes the entries of table with primary keys ids, or all of them if ids is empty
+// or ctx has no scope.
+func (c *Cacher) invalidate(ctx context.Context, table string, ids []any) error {
+	scope, ok := c.scope(ctx)
+	if len(ids) == 0 || !ok {
+		_, err := c.bump(ctx, table)
+		return err
+	}
+	version, err := c.version(ctx, table)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.entryKey(table, scope, version, id)
+	}
+	c.fillsMu.Lock()
+	for _, key := range keys {
+		if f, ok := c.fills[key]; ok {
+			f.stale = true
+		}
+	}
+	c.fillsMu.Unlock()
+	return c.cfg.Cache.Delete(ctx, keys...)
+}
+
+// scope returns the partition of ctx, reporting false when Scope is set but returns none.
+func (c *Cacher) scope(ctx context.Context) (string, bool) {
+	if c.cfg.Scope == nil {
+		return "", true
+	}
+	scope := c.cfg.Scope(ctx)
+	return scope, scope != ""
+}
+
+// version returns the current version of table, part of its keys: changing it invalidates the
+// whole table at once. It is stored in the Cache so every process sees it.
+func (c *Cacher) version(ctx context.Context, table string) (string, error) {
+	v, ok, err := c.cfg.Cache.Get(ctx, c.versionKey(table))
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return string(v), nil
+	}
+	// Unknown (e.g. evicted): start a new version, older entries may be stale
+	return c.bump(ctx, table)
+}
+
+// bump gives table a new version.
+func (c *Cacher) bump(ctx context.Context, table string) (string, error) {
+	v := strconv.FormatInt(time.Now().UnixNano(), 36)
+	return v, c.cfg.Cache.Set(ctx, c.versionKey(table), []byte(v), 0)
+}
+
+func (c *Cacher) versionKey(table string) string {
+	return c.cfg.Prefix + table + ":version"
+}
+
+func (c *Cacher) entryKey(table, scope, version string, id any) string {
+	return fmt.Sprintf("%s%s:%s:%s:%v", c.cfg.Prefix, table, scope, version, id)
+}
+
+func (c *Cacher) fail(err error) {
+	if c.cfg.OnError != nil {
+		c.cfg.OnError(err)
+	}
+}
+
+// written returns the primary keys of the entities written by op, nil for bulk writes.
+func written(ctx context.Context, op *repository.Operation, s *schema.Schema) []any {
+	switch op.Name {
+	case repository.OpUpdateWhere, repository.OpDeleteWhere, repository.OpPurge:
+		return nil
+	case repository.OpDeleteByID:
+		return idList(op.Args[1])
+	}
+	return ids(ctx, s, op.Args[0])
+}
+
+// ids returns the primary keys of the entities of arg, an entity or a slice of them.
+func ids(ctx context.Context, s *schema.Schema, arg any) []any {
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return nil
+	}
+	rv := reflect.Indirect(reflect.ValueOf(arg))
+	var out []any
+	add := func(e reflect.Value) {
+		e = reflect.Indirect(e)
+		if e.Kind() != reflect.Struct || e.Type() != s.ModelType {
+			return
+		}
+		if v, zero := pk.ValueOf(ctx, e); !zero {
+			out = append(out, v)
+		}
+	}
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := range rv.Len() {
+			add(rv.Index(i))
+		}
+	} else {
+		add(rv)
+	}
+	return out
+}
+
+// idList returns the primary keys of id, a key or a slice of them.
+func idList(id any) []any {
+	rv := reflect.ValueOf(id)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{id}
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+func encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode decodes data into out, reset first since gob leaves zero fields untouched.
+func decode(data []byte, out any) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("gormr: cannot decode cached entity into %T", out)
+	}
+	rv.Elem().SetZero()
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(out)
+}
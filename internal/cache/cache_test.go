@@ -0,0 +1,395 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+type Country struct {
+	ID   uint `gorm:"primaryKey"`
+	Code string
+	Name string
+}
+
+type Visit struct {
+	ID   uint `gorm:"primaryKey"`
+	Page string
+}
+
+// setupCache returns a repository caching Country and the number of queries it ran.
+func setupCache(t *testing.T, cfg Config) (*repository.Repository, *Cacher, *atomic.Int64) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&Country{}, &Visit{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	queries := &atomic.Int64{}
+	err = db.Callback().Query().Before("gorm:query").Register("test:count", func(*gorm.DB) {
+		queries.Add(1)
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	cfg.Models = []any{&Country{}}
+	c := New(cfg)
+	repo := repository.New(db)
+	repo.Use(c.Middleware())
+	return repo, c, queries
+}
+
+func getCountry(t *testing.T, repo *repository.Repository, ctx context.Context, id uint) Country {
+	t.Helper()
+	var got Country
+	if err := repo.GetByID(ctx, &Country{}, id, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	return got
+}
+
+func TestCacher_ReadThrough(t *testing.T) {
+	repo, _, queries := setupCache(t, Config{})
+	ctx := context.Background()
+	fr := &Country{Code: "FR", Name: "France"}
+	if err := repo.Create(ctx, fr); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	for range 3 {
+		if got := getCountry(t, repo, ctx, fr.ID); got.Name != "France" {
+			t.Fatalf("expected France, got %+v", got)
+		}
+	}
+	if n := queries.Load(); n != 1 {
+		t.Errorf("expected 1 query, got %d", n)
+	}
+
+	// Records not found are not cached
+	getCountry(t, repo, ctx, 99)
+	getCountry(t, repo, ctx, 99)
+	if n := queries.Load(); n != 3 {
+		t.Errorf("expected misses to query, got %d queries", n)
+	}
+
+	// Options and models not cached go to the database
+	var got Country
+	if err := repo.GetByID(ctx, &Country{}, fr.ID, &got, repository.WithTrashed()); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	v := &Visit{Page: "/"}
+	if err := repo.Create(ctx, v); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var visit Visit
+	_ = repo.GetByID(ctx, &Visit{}, v.ID, &visit)
+	_ = repo.GetByID(ctx, &Visit{}, v.ID, &visit)
+	if n := queries.Load(); n != 6 {
+		t.Errorf("expected uncached reads to query, got %d queries", n)
+	}
+}
+
+// CountryCode reads the code of a Country.
+type CountryCode struct {
+	ID   uint
+	Code string
+}
+
+func TestCacher_DTO(t *testing.T) {
+	repo, _, queries := setupCache(t, Config{})
+	ctx := context.Background()
+	fr := &Country{Code: "FR", Name: "France"}
+	if err := repo.Create(ctx, fr); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var code CountryCode
+	for range 2 {
+		if err := repo.GetByID(ctx, &Country{}, fr.ID, &code); err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+	}
+	if code.Code != "FR" || queries.Load() != 2 {
+		t.Errorf("expected DTO reads to skip the cache, got %+v after %d queries", code, queries.Load())
+	}
+	if got := getCountry(t, repo, ctx, fr.ID); got.Name != "France" {
+		t.Errorf("expected the model read not to see the DTO, got %+v", got)
+	}
+	if got := getCountry(t, repo, ctx, fr.ID); got.Name != "France" || queries.Load() != 3 {
+		t.Errorf("expected the model read to be cached, got %+v after %d queries", got, queries.Load())
+	}
+	if err := repo.GetByID(ctx, &Country{}, fr.ID, &code); err != nil || code.Code != "FR" {
+		t.Errorf("expected the DTO read not to see the cached model, got %+v (%v)", code, err)
+	}
+}
+
+func TestCacher_Invalidation(t *testing.T) {
+	repo, c, queries := setupCache(t, Config{})
+	ctx := context.Background()
+	fr := &Country{Code: "FR", Name: "France"}
+	de := &Country{Code: "DE", Name: "Germany"}
+	for _, e := range []*Country{fr, de} {
+		if err := repo.Create(ctx, e); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	getCountry(t, repo, ctx, fr.ID)
+
+	fr.Name = "République française"
+	if err := repo.Update(ctx, fr); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := getCountry(t, repo, ctx, fr.ID); got.Name != fr.Name {
+		t.Errorf("expected the update to invalidate the entry, got %+v", got)
+	}
+
+	// Writes in a transaction invalidate once committed
+	getCountry(t, repo, ctx, de.ID)
+	err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.UpdateMap(ctx, de, map[string]any{"name": "Deutschland"}); err != nil {
+			return err
+		}
+		if got := getCountry(t, repo, context.Background(), de.ID); got.Name != "Germany" {
+			t.Errorf("expected the cached entry before commit, got %+v", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction failed: %v", err)
+	}
+	if got := getCountry(t, repo, ctx, de.ID); got.Name != "Deutschland" {
+		t.Errorf("expected the commit to invalidate the entry, got %+v", got)
+	}
+
+	// Bulk writes invalidate the table
+	getCountry(t, repo, ctx, fr.ID)
+	if _, err := repo.UpdateWhere(ctx, repository.NewSpec(&Country{}).Eq("code", "FR"), map[string]any{"name": "France"}); err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if got := getCountry(t, repo, ctx, fr.ID); got.Name != "France" {
+		t.Errorf("expected UpdateWhere to invalidate the table, got %+v", got)
+	}
+
+	if err := repo.DeleteByID(ctx, &Country{}, fr.ID); err != nil {
+		t.Fatalf("DeleteByID failed: %v", err)
+	}
+	if got := getCountry(t, repo, ctx, fr.ID); got.ID != 0 {
+		t.Errorf("expected the delete to invalidate the entry, got %+v", got)
+	}
+
+	// Manual invalidation
+	getCountry(t, repo, ctx, de.ID)
+	before := queries.Load()
+	if err := c.Invalidate(ctx, repo, &Country{}, de.ID); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+	getCountry(t, repo, ctx, de.ID)
+	if queries.Load() != before+1 {
+		t.Errorf("expected Invalidate to drop the entry")
+	}
+}
+
+func TestCacher_Singleflight(t *testing.T) {
+	repo, _, queries := setupCache(t, Config{})
+	ctx := context.Background()
+	fr := &Country{Code: "FR", Name: "France"}
+	if err := repo.Create(ctx, fr); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Slow the query down so concurrent reads overlap
+	release := make(chan struct{})
+	repo.Use(func(next repository.Handler) repository.Handler {
+		return func(ctx context.Context, op *repository.Operation) (any, error) {
+			<-release
+			return next(ctx, op)
+		}
+	})
+	var wg sync.WaitGroup
+	results := make([]Country, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = repo.GetByID(ctx, &Country{}, fr.ID, &results[i])
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := queries.Load(); n != 1 {
+		t.Errorf("expected concurrent misses to share 1 query, got %d", n)
+	}
+	for _, r := range results {
+		if r.Name != "France" {
+			t.Errorf("expected every caller to get France, got %+v", r)
+		}
+	}
+}
+
+// gate holds a read after its query until released.
+type gate struct {
+	read, release chan struct{}
+}
+
+func TestCacher_InvalidatedFill(t *testing.T) {
+	repo, _, _ := setupCache(t, Config{})
+	ctx := context.Background()
+	fr := &Country{Code: "FR", Name: "France"}
+	de := &Country{Code: "DE", Name: "Germany"}
+	for _, e := range []*Country{fr, de} {
+		if err := repo.Create(ctx, e); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	var held atomic.Pointer[gate]
+	repo.Use(func(next repository.Handler) repository.Handler {
+		return func(ctx context.Context, op *repository.Operation) (any, error) {
+			res, err := next(ctx, op)
+			if g := held.Load(); op.Name == repository.OpGetByID && g != nil && held.CompareAndSwap(g, nil) {
+				close(g.read)
+				<-g.release
+			}
+			return res, err
+		}
+	})
+
+	cases := []struct {
+		country *Country
+		name    string
+		write   func() error
+	}{
+		{fr, "Francia", func() error {
+			return repo.UpdateMap(ctx, fr, map[string]any{"name": "Francia"})
+		}},
+		{de, "Deutschland", func() error {
+			_, err := repo.UpdateWhere(ctx, repository.NewSpec(&Country{}).Eq("code", "DE"), map[string]any{"name": "Deutschland"})
+			return err
+		}},
+	}
+	for _, c := range cases {
+		// The write invalidates the entry while the read of the previous row fills it
+		g := &gate{read: make(chan struct{}), release: make(chan struct{})}
+		held.Store(g)
+		done := make(chan Country)
+		go func() {
+			var got Country
+			_ = repo.GetByID(ctx, &Country{}, c.country.ID, &got)
+			done <- got
+		}()
+		<-g.read
+		if err := c.write(); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		close(g.release)
+		<-done
+		if got := getCountry(t, repo, ctx, c.country.ID); got.Name != c.name {
+			t.Errorf("expected the fill of the invalidated entry to be dropped, got %+v", got)
+		}
+	}
+}
+
+func TestCacher_Scope(t *testing.T) {
+	type scopeKey struct{}
+	scope := func(ctx context.Context) string {
+		s, _ := ctx.Value(scopeKey{}).(string)
+		return s
+	}
+	repo, _, queries := setupCache(t, Config{Scope: scope})
+	a := context.WithValue(context.Background(), scopeKey{}, "a")
+	b := context.WithValue(context.Background(), scopeKey{}, "b")
+	fr := &Country{Code: "FR", Name: "France"}
+	if err := repo.Create(a, fr); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	getCountry(t, repo, a, fr.ID)
+	getCountry(t, repo, b, fr.ID)
+	getCountry(t, repo, context.Background(), fr.ID)
+	getCountry(t, repo, context.Background(), fr.ID)
+	if n := queries.Load(); n != 4 {
+		t.Errorf("expected scopes to be cached apart and unscoped reads to skip the cache, got %d queries", n)
+	}
+	getCountry(t, repo, a, fr.ID)
+	getCountry(t, repo, b, fr.ID)
+	if n := queries.Load(); n != 4 {
+		t.Errorf("expected scoped reads to hit, got %d queries", n)
+	}
+
+	// An unscoped write invalidates every scope
+	if err := repo.UpdateMap(context.Background(), fr, map[string]any{"name": "Francia"}); err != nil {
+		t.Fatalf("UpdateMap failed: %v", err)
+	}
+	if got := getCountry(t, repo, b, fr.ID); got.Name != "Francia" {
+		t.Errorf("expected the unscoped write to invalidate scope b, got %+v", got)
+	}
+}
+
+type failingCache struct{ *LRU }
+
+func (*failingCache) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("down")
+}
+
+func TestCacher_CacheErrors(t *testing.T) {
+	var errs atomic.Int64
+	repo, _, _ := setupCache(t, Config{
+		Cache:   &failingCache{NewLRU(10)},
+		OnError: func(error) { errs.Add(1) },
+	})
+	ctx := context.Background()
+	fr := &Country{Code: "FR", Name: "France"}
+	if err := repo.Create(ctx, fr); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if got := getCountry(t, repo, ctx, fr.ID); got.Name != "France" {
+		t.Errorf("expected to fall back to the database, got %+v", got)
+	}
+	if errs.Load() == 0 {
+		t.Errorf("expected OnError to be called")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), time.Second)
+	_, _, _ = c.Get(ctx, "a")
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Errorf("expected the least recently used entry to be evicted")
+	}
+	if v, ok, _ := c.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Errorf("expected a to be kept, got %q", v)
+	}
+
+	_ = c.Set(ctx, "d", []byte("4"), time.Second)
+	now = now.Add(2 * time.Second)
+	if _, ok, _ := c.Get(ctx, "d"); ok {
+		t.Errorf("expected d to be expired")
+	}
+	_ = c.Delete(ctx, "a", "missing")
+	if c.Len() != 0 {
+		t.Errorf("expected the cache to be empty, got %d entries", c.Len())
+	}
+}
@@ -0,0 +1,200 @@
+// Package defaults fills fields on write, as a GORM plugin: generated primary keys (UUIDv7, ULID,
+// snowflake), created_by/updated_by from the actor in context and custom default providers
+// declared with struct tags.
+package defaults
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// tagName is the struct tag declaring the filled fields, e.g. `gormr:"default:uuidv7"` or
+// `gormr:"created_by"`.
+const tagName = "gormr"
+
+// Config configures the defaults Plugin. Zero values select the defaults.
+type Config struct {
+	// Custom providers usable in `gormr:"default:<name>"` tags; they may replace the built-ins
+	Providers map[string]Provider
+	// Node ID of the snowflake provider, between 0 and 1023; must be unique per process
+	NodeID int64
+	// Clock of the "now" and snowflake providers (default: GORM's clock). GORM's own timestamps
+	// (CreatedAt, UpdatedAt) keep following gorm.Config.NowFunc.
+	Now func() time.Time
+	// Column set to the actor on create (default "created_by"). A field tagged `gormr:"created_by"` is used too.
+	CreatedByColumn string
+	// Column set to the actor on create and update (default "updated_by"). A field tagged `gormr:"updated_by"` is used too.
+	UpdatedByColumn string
+}
+
+// Plugin is a GORM plugin filling fields on create and update.
+//
+// On create, zero fields tagged `gormr:"default:<provider>"` get the value of the provider, and
+// the created_by and updated_by fields get the actor set by repository.WithActor. On update, the
+// updated_by column is set to the actor, even when the update selects other columns only.
+// Explicit values are never overwritten on create, and nothing is filled without an actor.
+// UpdateColumn and UpdateColumns skip the plugin, like GORM's own timestamps.
+type Plugin struct {
+	cfg       Config
+	providers map[string]Provider
+}
+
+// New creates the defaults Plugin; register it with db.Use.
+func New(cfg Config) *Plugin {
+	if cfg.CreatedByColumn == "" {
+		cfg.CreatedByColumn = "created_by"
+	}
+	if cfg.UpdatedByColumn == "" {
+		cfg.UpdatedByColumn = "updated_by"
+	}
+	return &Plugin{cfg: cfg}
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string {
+	return "gormr:defaults"
+}
+
+// Initialize registers the plugin callbacks on db.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	if p.cfg.NodeID < 0 || p.cfg.NodeID > maxNode {
+		return fmt.Errorf("gormr: snowflake node id %d out of range [0, %d]", p.cfg.NodeID, maxNode)
+	}
+	now := p.cfg.Now
+	if now == nil {
+		now = db.Config.NowFunc
+	}
+	p.providers = builtins(p.cfg.NodeID, now)
+	for name, provider := range p.cfg.Providers {
+		p.providers[name] = provider
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:before_create").Register("gormr:defaults_create", p.create); err != nil {
+		return err
+	}
+	return cb.Update().Before("gorm:update").Register("gormr:defaults_update", p.update)
+}
+
+// filled lists the fields of a model the plugin fills.
+type filled struct {
+	// Fields with a default provider, and the provider names
+	defaults  []*schema.Field
+	providers []string
+	createdBy *schema.Field
+	updatedBy *schema.Field
+}
+
+// fields returns the fields of s the plugin fills.
+func (p *Plugin) fields(s *schema.Schema) filled {
+	var out filled
+	for _, f := range s.Fields {
+		tags := schema.ParseTagSetting(f.Tag.Get(tagName), ";")
+		if name, ok := tags["DEFAULT"]; ok {
+			out.defaults = append(out.defaults, f)
+			out.providers = append(out.providers, name)
+		}
+		if _, ok := tags["CREATED_BY"]; ok || (out.createdBy == nil && f.DBName == p.cfg.CreatedByColumn) {
+			out.createdBy = f
+		}
+		if _, ok := tags["UPDATED_BY"]; ok || (out.updatedBy == nil && f.DBName == p.cfg.UpdatedByColumn) {
+			out.updatedBy = f
+		}
+	}
+	return out
+}
+
+// create fills the fields of the inserted entities.
+func (p *Plugin) create(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	fs := p.fields(db.Statement.Schema)
+	ctx := db.Statement.Context
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			if err := p.fill(ctx, db.Statement.Schema, fs, reflect.Indirect(rv.Index(i))); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := p.fill(ctx, db.Statement.Schema, fs, rv); err != nil {
+			db.AddError(err)
+		}
+	}
+}
+
+// fill sets the zero fields of entity rv.
+func (p *Plugin) fill(ctx context.Context, s *schema.Schema, fs filled, rv reflect.Value) error {
+	if rv.Kind() != reflect.Struct || rv.Type() != s.ModelType {
+		return nil
+	}
+	for i, f := range fs.defaults {
+		if _, zero := f.ValueOf(ctx, rv); !zero {
+			continue
+		}
+		provider, ok := p.providers[fs.providers[i]]
+		if !ok {
+			return fmt.Errorf("gormr: unknown default provider %q on %s.%s", fs.providers[i], s.Name, f.Name)
+		}
+		v, err := provider(ctx)
+		if err != nil {
+			return err
+		}
+		if err := f.Set(ctx, rv, v); err != nil {
+			return fmt.Errorf("gormr: cannot set %s.%s to %T: %w", s.Name, f.Name, v, err)
+		}
+	}
+	actor, ok := repository.ActorFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	for _, f := range []*schema.Field{fs.createdBy, fs.updatedBy} {
+		if f == nil {
+			continue
+		}
+		if _, zero := f.ValueOf(ctx, rv); !zero {
+			continue
+		}
+		if err := f.Set(ctx, rv, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update sets the updated_by column to the actor.
+func (p *Plugin) update(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SkipHooks {
+		return
+	}
+	f := p.fields(db.Statement.Schema).updatedBy
+	if f == nil {
+		return
+	}
+	actor, ok := repository.ActorFromContext(db.Statement.Context)
+	if !ok {
+		return
+	}
+	// Set the column on a copy of map updates: the caller's map is left untouched
+	if changes, ok := db.Statement.Dest.(map[string]any); ok {
+		db.Statement.Dest = maps.Clone(changes)
+	}
+	db.Statement.SetColumn(f.DBName, actor, true)
+	if selects := db.Statement.Selects; len(selects) > 0 && !slices.Contains(selects, "*") &&
+		!slices.Contains(selects, f.DBName) && !slices.Contains(selects, f.Name) {
+		db.Statement.Selects = append(selects, f.DBName)
+	}
+}
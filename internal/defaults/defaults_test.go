@@ -0,0 +1,182 @@
+package defaults
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+type Order struct {
+	ID        string `gorm:"primaryKey" gormr:"default:uuidv7"`
+	Reference string `gormr:"default:ulid"`
+	Code      string `gormr:"default:code"`
+	Total     int
+	CreatedBy string
+	UpdatedBy string
+	CreatedAt time.Time
+}
+
+type Event struct {
+	ID     int64     `gorm:"primaryKey;autoIncrement:false" gormr:"default:snowflake"`
+	Token  uuid.UUID `gormr:"default:uuid"`
+	Author string    `gormr:"created_by"`
+	At     time.Time `gormr:"default:now"`
+}
+
+type Broken struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gormr:"default:missing"`
+}
+
+var clock = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func setupDefaults(t *testing.T) *repository.Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	cfg := Config{
+		NodeID: 7,
+		Now:    func() time.Time { return clock },
+		Providers: map[string]Provider{
+			"code": func(context.Context) (any, error) { return "ORD-1", nil },
+		},
+	}
+	if err := db.Use(New(cfg)); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&Order{}, &Event{}, &Broken{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return repository.New(db)
+}
+
+func TestDefaults_Create(t *testing.T) {
+	repo := setupDefaults(t)
+	ctx := repository.WithActor(context.Background(), "alice")
+
+	o := &Order{Total: 10}
+	if err := repo.Create(ctx, o); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	id, err := uuid.Parse(o.ID)
+	if err != nil || id.Version() != 7 {
+		t.Errorf("expected a UUIDv7 primary key, got %q (%v)", o.ID, err)
+	}
+	if len(o.Reference) != 26 {
+		t.Errorf("expected a ULID reference, got %q", o.Reference)
+	}
+	if o.Code != "ORD-1" {
+		t.Errorf("expected the custom provider to set Code, got %q", o.Code)
+	}
+	if o.CreatedBy != "alice" || o.UpdatedBy != "alice" {
+		t.Errorf("expected created_by and updated_by to be alice, got %q and %q", o.CreatedBy, o.UpdatedBy)
+	}
+	if o.CreatedAt.Equal(clock) || time.Since(o.CreatedAt) > time.Minute {
+		t.Errorf("expected CreatedAt from GORM's clock, not the plugin's, got %v", o.CreatedAt)
+	}
+
+	// Explicit values are kept
+	o2 := &Order{ID: "custom", Code: "X", CreatedBy: "bob"}
+	if err := repo.Create(ctx, o2); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if o2.ID != "custom" || o2.Code != "X" || o2.CreatedBy != "bob" {
+		t.Errorf("expected explicit values to be kept, got %+v", o2)
+	}
+
+	// No actor, no created_by
+	o3 := &Order{}
+	if err := repo.Create(context.Background(), o3); err != nil || o3.CreatedBy != "" {
+		t.Errorf("expected no created_by without actor, got %q (%v)", o3.CreatedBy, err)
+	}
+}
+
+func TestDefaults_Batch(t *testing.T) {
+	repo := setupDefaults(t)
+	ctx := repository.WithActor(context.Background(), "alice")
+
+	events := []Event{{}, {}, {}}
+	if err := repo.CreateBatch(ctx, &events, 2); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	seen := map[int64]bool{}
+	for _, e := range events {
+		if e.ID == 0 || seen[e.ID] {
+			t.Errorf("expected unique snowflake ids, got %d", e.ID)
+		}
+		seen[e.ID] = true
+		if node := e.ID >> sequenceBits & maxNode; node != 7 {
+			t.Errorf("expected node 7 in the snowflake id, got %d", node)
+		}
+		if e.Token == uuid.Nil || e.Author != "alice" || !e.At.Equal(clock) {
+			t.Errorf("expected token, author and time to be set, got %+v", e)
+		}
+	}
+	if events[0].ID >= events[1].ID || events[1].ID >= events[2].ID {
+		t.Errorf("expected increasing snowflake ids, got %d %d %d", events[0].ID, events[1].ID, events[2].ID)
+	}
+}
+
+func TestDefaults_UpdatedBy(t *testing.T) {
+	repo := setupDefaults(t)
+	o := &Order{Total: 1}
+	if err := repo.Create(repository.WithActor(context.Background(), "alice"), o); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	bob := repository.WithActor(context.Background(), "bob")
+	o.Total = 2
+	if err := repo.UpdateFields(bob, o, "Total"); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	var got Order
+	if err := repo.GetByID(bob, &Order{}, o.ID, &got); err != nil || got.UpdatedBy != "bob" || got.CreatedBy != "alice" {
+		t.Errorf("expected updated_by bob and created_by alice, got %+v (%v)", got, err)
+	}
+
+	carol := repository.WithActor(context.Background(), "carol")
+	changes := map[string]any{"total": 3}
+	if _, err := repo.UpdateWhere(carol, repository.NewSpec(&Order{}).Eq("id", o.ID), changes); err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("expected the caller's map to be left untouched, got %v", changes)
+	}
+	if err := repo.GetByID(carol, &Order{}, o.ID, &got); err != nil || got.UpdatedBy != "carol" {
+		t.Errorf("expected updated_by carol, got %+v (%v)", got, err)
+	}
+}
+
+func TestDefaults_Errors(t *testing.T) {
+	repo := setupDefaults(t)
+	err := repo.Create(context.Background(), &Broken{})
+	if err == nil || !strings.Contains(err.Error(), `unknown default provider "missing"`) {
+		t.Errorf("expected unknown provider error, got %v", err)
+	}
+
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err := db.Use(New(Config{NodeID: 1024})); err == nil {
+		t.Errorf("expected an out of range node id to be rejected")
+	}
+}
+
+func TestSnowflake_ClockBackwards(t *testing.T) {
+	now := clock
+	sf := &snowflake{now: func() time.Time { return now }}
+	if _, err := sf.next(); err != nil {
+		t.Fatalf("next failed: %v", err)
+	}
+	now = now.Add(-time.Second)
+	if _, err := sf.next(); !errors.Is(err, ErrClockBackwards) {
+		t.Errorf("expected ErrClockBackwards, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package defaults
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// Names of the built-in providers.
+const (
+	// UUIDv7: time-ordered UUID (RFC 9562), the recommended primary key
+	ProviderUUIDv7 = "uuidv7"
+	// UUIDv4: random UUID
+	ProviderUUID = "uuid"
+	// ULID: time-ordered, 26 characters, monotonic within a millisecond
+	ProviderULID = "ulid"
+	// Snowflake: time-ordered int64 made of a timestamp, the node ID and a sequence
+	ProviderSnowflake = "snowflake"
+	// Current time, from Config.Now (default: the GORM clock)
+	ProviderNow = "now"
+)
+
+// ErrClockBackwards is returned by the snowflake provider when the clock moved backwards.
+var ErrClockBackwards = errors.New("gormr: clock moved backwards, cannot generate snowflake id")
+
+// Provider returns the default value of a field. The value must be assignable or convertible to
+// the field type, or a driver.Valuer (e.g. uuid.UUID on a string field).
+type Provider func(ctx context.Context) (any, error)
+
+// builtins returns the built-in providers.
+func builtins(node int64, now func() time.Time) map[string]Provider {
+	sf := &snowflake{node: node, now: now}
+	return map[string]Provider{
+		ProviderUUIDv7: func(context.Context) (any, error) { return uuid.NewV7() },
+		ProviderUUID:   func(context.Context) (any, error) { return uuid.New(), nil },
+		ProviderULID:   func(context.Context) (any, error) { return ulid.Make().String(), nil },
+		ProviderSnowflake: func(context.Context) (any, error) {
+			return sf.next()
+		},
+		ProviderNow: func(context.Context) (any, error) { return now(), nil },
+	}
+}
+
+// Layout of snowflake ids: 41 bits of milliseconds since snowflakeEpoch, 10 bits of node and
+// 12 bits of sequence.
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	maxNode      = 1<<nodeBits - 1
+	maxSequence  = 1<<sequenceBits - 1
+)
+
+// snowflakeEpoch is the origin of snowflake timestamps (2024-01-01 UTC), in milliseconds.
+const snowflakeEpoch = 1704067200000
+
+// snowflake generates the ids of one node.
+type snowflake struct {
+	node int64
+	now  func() time.Time
+
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+func (s *snowflake) next() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms := s.now().UnixMilli() - snowflakeEpoch
+	if ms < s.last {
+		return 0, ErrClockBackwards
+	}
+	if ms == s.last {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			// Sequence exhausted: wait for the next millisecond
+			for ms <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				ms = s.now().UnixMilli() - snowflakeEpoch
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = ms
+	return ms<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence, nil
+}
@@ -0,0 +1,107 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidKey is returned for keys with an invalid ID or size.
+	ErrInvalidKey = errors.New("gormr: invalid encryption key")
+	// ErrUnknownKey is returned when decrypting a value encrypted with a key the provider does not know.
+	ErrUnknownKey = errors.New("gormr: unknown encryption key")
+	// ErrDecrypt is returned for values that cannot be decrypted (corrupted, tampered with or
+	// moved from another column).
+	ErrDecrypt = errors.New("gormr: cannot decrypt value")
+	// ErrNotSearchable is returned by lookups on encrypted fields not in deterministic mode.
+	ErrNotSearchable = errors.New("gormr: encrypted field is not deterministic and cannot be searched")
+)
+
+// prefix heads encrypted values: "enc:<key id>:<base64 of nonce and ciphertext>". Values
+// without it are read as they are, so columns can be encrypted progressively.
+const prefix = "enc:"
+
+// encrypt encrypts plaintext with the current key.
+func encrypt(ctx context.Context, keys KeyProvider, column string, plaintext []byte, deterministic bool) (string, error) {
+	key, err := keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	return encryptWith(key, column, plaintext, deterministic)
+}
+
+// encryptWith encrypts plaintext with key. The column ("table.column") is authenticated, so the
+// value cannot be moved to another column. Deterministic values derive their nonce from the
+// plaintext.
+func encryptWith(key Key, column string, plaintext []byte, deterministic bool) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if deterministic {
+		copy(nonce, syntheticNonce(key, column, plaintext))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(column))
+	return prefix + key.ID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt decrypts a value produced by encrypt; values without prefix are returned unchanged.
+func decrypt(ctx context.Context, keys KeyProvider, column string, value string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return []byte(value), nil
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return nil, ErrDecrypt
+	}
+	key, err := keys.Key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	n := aead.NonceSize()
+	plaintext, err := aead.Open(nil, sealed[:n], sealed[n:], []byte(column))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidKey, key.ID, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// syntheticNonce returns the nonce of a deterministic value: an HMAC of the column and plaintext
+// under a key derived from the encryption key, so equal plaintexts give equal ciphertexts.
+func syntheticNonce(key Key, column string, plaintext []byte) []byte {
+	derive := hmac.New(sha256.New, key.Secret)
+	derive.Write([]byte("gormr:deterministic"))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write(plaintext)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,454 @@
+// Package encryption encrypts the fields tagged `gormr:"encrypted"` with AES-GCM before they are
+// written and decrypts them when they are read, as a GORM plugin.
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// tagName is the struct tag marking encrypted fields: `gormr:"encrypted"`, or
+// `gormr:"encrypted:deterministic"` for fields looked up by equality.
+const tagName = "gormr"
+
+// deterministic is the value of the encrypted tag selecting the deterministic mode.
+const deterministic = "deterministic"
+
+// restoreKey stores in the statement settings the plaintexts to put back after a write.
+const restoreKey = "gormr:encryption_restore"
+
+// Config configures the Encryptor.
+type Config struct {
+	// Provider of the encryption keys (required)
+	Keys KeyProvider
+}
+
+// Encryptor is a GORM plugin encrypting the fields tagged `gormr:"encrypted"`.
+//
+// Non-zero string and []byte fields are encrypted with the current key of the KeyProvider before
+// creates and updates (struct and map updates alike) and put back in plaintext afterwards, and
+// they are decrypted after queries, into the model, DTOs or maps, and in the rows streamed by
+// repository.Iterate. Values carry the ID of their key, so keys can be rotated. Randomized values (the default) cannot be searched; deterministic values
+// (`gormr:"encrypted:deterministic"`) encrypt equal plaintexts equally, which allows equality
+// lookups but reveals which rows share a value. Lookups search the ciphertexts under every key,
+// so rows written before a rotation are still found.
+type Encryptor struct {
+	keys KeyProvider
+	db   *gorm.DB
+}
+
+// New creates the Encryptor; register it with db.Use, and its Middleware on the Repository to
+// search deterministic fields with GetByField.
+func New(cfg Config) (*Encryptor, error) {
+	if cfg.Keys == nil {
+		return nil, errors.New("gormr: encryption requires a key provider")
+	}
+	return &Encryptor{keys: cfg.Keys}, nil
+}
+
+// Name returns the name of the plugin.
+func (e *Encryptor) Name() string {
+	return "gormr:encryption"
+}
+
+// Initialize registers the plugin callbacks on db.
+func (e *Encryptor) Initialize(db *gorm.DB) error {
+	e.db = db
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("gormr:encrypt_create", e.encrypt),
+		cb.Create().After("gorm:create").Register("gormr:decrypt_create", e.restore),
+		cb.Update().Before("gorm:update").Register("gormr:encrypt_update", e.encrypt),
+		cb.Update().After("gorm:update").Register("gormr:decrypt_update", e.restore),
+		cb.Query().After("gorm:query").Register("gormr:decrypt_query", e.decrypt),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Middleware returns the repository middleware replacing the value searched by GetByField on
+// deterministic fields with its ciphertexts (see Lookup), and rejecting searches on randomized
+// ones with ErrNotSearchable.
+func (e *Encryptor) Middleware() repository.Middleware {
+	return func(next repository.Handler) repository.Handler {
+		return func(ctx context.Context, op *repository.Operation) (any, error) {
+			if op.Name == repository.OpGetByField {
+				values, err := e.Lookup(ctx, op.Model, op.Args[1].(string), op.Args[2])
+				if err != nil {
+					return nil, err
+				}
+				if len(values) == 1 {
+					op.Args[2] = values[0]
+				} else {
+					op.Args[2] = values
+				}
+			}
+			return next(ctx, op)
+		}
+	}
+}
+
+// Lookup returns the values column field (column or struct field name) of model may hold for
+// value, to search them with an IN condition (e.g. Spec.Where("email IN ?", values)): the
+// ciphertexts of value under every key of the KeyProvider for deterministic fields, and value
+// itself for fields not encrypted.
+func (e *Encryptor) Lookup(ctx context.Context, model any, field string, value any) ([]any, error) {
+	stmt := &gorm.Statement{DB: e.db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	f := stmt.Schema.LookUpField(field)
+	if f == nil {
+		return []any{value}, nil
+	}
+	mode, ok := encryptedMode(f)
+	if !ok {
+		return []any{value}, nil
+	}
+	if mode != deterministic {
+		return nil, fmt.Errorf("%w: %s.%s", ErrNotSearchable, stmt.Schema.Name, f.Name)
+	}
+	plaintext, ok := bytesOf(value)
+	if !ok {
+		return nil, fmt.Errorf("gormr: cannot search encrypted field %s.%s with %T", stmt.Schema.Name, f.Name, value)
+	}
+	keys, err := e.keys.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, 0, len(keys))
+	for _, key := range keys {
+		v, err := encryptWith(key, column(stmt.Schema, f), plaintext, true)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// secret is an encrypted field of a model.
+type secret struct {
+	field         *schema.Field
+	column        string
+	deterministic bool
+}
+
+// secrets returns the encrypted fields of s.
+func secrets(s *schema.Schema) ([]secret, error) {
+	var out []secret
+	for _, f := range s.Fields {
+		mode, ok := encryptedMode(f)
+		if !ok {
+			continue
+		}
+		if f.FieldType.Kind() != reflect.String && f.FieldType != reflect.TypeFor[[]byte]() {
+			return nil, fmt.Errorf("gormr: encrypted field %s.%s must be a string or []byte", s.Name, f.Name)
+		}
+		out = append(out, secret{field: f, column: column(s, f), deterministic: mode == deterministic})
+	}
+	return out, nil
+}
+
+// encryptedMode returns the mode of the encrypted tag of f, if any.
+func encryptedMode(f *schema.Field) (string, bool) {
+	mode, ok := schema.ParseTagSetting(f.Tag.Get(tagName), ";")["ENCRYPTED"]
+	return mode, ok
+}
+
+// column returns the name authenticated with the values of f.
+func column(s *schema.Schema, f *schema.Field) string {
+	return s.Table + "." + f.DBName
+}
+
+// restoration is a field to set back to its plaintext after a write.
+type restoration struct {
+	entity reflect.Value
+	field  *schema.Field
+	value  any
+}
+
+// encrypt encrypts the fields written by the statement.
+func (e *Encryptor) encrypt(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	s := db.Statement.Schema
+	fields, err := secrets(s)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if len(fields) == 0 {
+		return
+	}
+	ctx := db.Statement.Context
+
+	if changes, ok := db.Statement.Dest.(map[string]any); ok {
+		changes = maps.Clone(changes)
+		for key, v := range changes {
+			for _, sf := range fields {
+				if key != sf.field.DBName && key != sf.field.Name {
+					continue
+				}
+				if changes[key], err = e.seal(ctx, sf, v); err != nil {
+					db.AddError(err)
+					return
+				}
+			}
+		}
+		db.Statement.Dest = changes
+	}
+
+	var restore []restoration
+	defer func() { db.Statement.Settings.Store(restoreKey, restore) }()
+	for _, rv := range entities(db) {
+		for _, sf := range fields {
+			v, zero := sf.field.ValueOf(ctx, rv)
+			if zero {
+				continue
+			}
+			sealed, err := e.seal(ctx, sf, v)
+			if err == nil {
+				err = sf.field.Set(ctx, rv, sealed)
+			}
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+			restore = append(restore, restoration{entity: rv, field: sf.field, value: v})
+		}
+	}
+}
+
+// seal returns the encrypted value of v, with the type of v. Nil and empty values are kept.
+func (e *Encryptor) seal(ctx context.Context, sf secret, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	plaintext, ok := bytesOf(v)
+	if !ok {
+		return nil, fmt.Errorf("gormr: cannot encrypt %s with %T", sf.column, v)
+	}
+	if len(plaintext) == 0 {
+		return v, nil
+	}
+	sealed, err := encrypt(ctx, e.keys, sf.column, plaintext, sf.deterministic)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.([]byte); ok {
+		return []byte(sealed), nil
+	}
+	return sealed, nil
+}
+
+// restore puts back the plaintexts of the fields encrypted by encrypt, even if the write failed.
+func (e *Encryptor) restore(db *gorm.DB) {
+	v, ok := db.Statement.Settings.LoadAndDelete(restoreKey)
+	if !ok {
+		return
+	}
+	for _, r := range v.([]restoration) {
+		_ = r.field.Set(db.Statement.Context, r.entity, r.value)
+	}
+}
+
+// decrypt decrypts the encrypted columns read by the statement: into entities of the model, into
+// other structs (DTOs), whose fields are matched to the model columns by column name, and into
+// maps keyed by column name. Reads given repository.RawValues are left encrypted.
+func (e *Encryptor) decrypt(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || repository.IsRawRead(db) {
+		return
+	}
+	fields, err := secrets(db.Statement.Schema)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if len(fields) == 0 {
+		return
+	}
+	ctx := db.Statement.Context
+	targets := map[reflect.Type][]target{}
+	for _, rv := range rows(db.Statement.ReflectValue) {
+		if rv.Kind() == reflect.Map {
+			err = e.openMap(ctx, fields, rv)
+		} else {
+			ts, ok := targets[rv.Type()]
+			if !ok {
+				ts, err = targetsOf(db, fields, rv.Type())
+				targets[rv.Type()] = ts
+			}
+			if err == nil {
+				err = e.openStruct(ctx, ts, rv)
+			}
+		}
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+}
+
+// AfterScan decrypts a row scanned by repository.Iterate, which does not run the query callbacks.
+func (e *Encryptor) AfterScan(db *gorm.DB) {
+	e.decrypt(db)
+}
+
+// target is a struct field holding an encrypted column.
+type target struct {
+	field  *schema.Field
+	secret secret
+}
+
+// targetsOf returns the fields of t, a struct read by the statement, holding the encrypted fields.
+func targetsOf(db *gorm.DB, fields []secret, t reflect.Type) ([]target, error) {
+	var out []target
+	if t == db.Statement.Schema.ModelType {
+		for _, sf := range fields {
+			out = append(out, target{field: sf.field, secret: sf})
+		}
+		return out, nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(reflect.New(t).Interface()); err != nil {
+		if errors.Is(err, schema.ErrUnsupportedDataType) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, sf := range fields {
+		if f := stmt.Schema.FieldsByDBName[sf.field.DBName]; f != nil {
+			out = append(out, target{field: f, secret: sf})
+		}
+	}
+	return out, nil
+}
+
+// openStruct decrypts the targets of rv.
+func (e *Encryptor) openStruct(ctx context.Context, targets []target, rv reflect.Value) error {
+	for _, t := range targets {
+		v, zero := t.field.ValueOf(ctx, rv)
+		if zero {
+			continue
+		}
+		plaintext, err := e.open(ctx, t.secret, v)
+		if err != nil {
+			return err
+		}
+		if err := t.field.Set(ctx, rv, plaintext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openMap decrypts the encrypted columns of rv, a map keyed by column name.
+func (e *Encryptor) openMap(ctx context.Context, fields []secret, rv reflect.Value) error {
+	for _, sf := range fields {
+		key := reflect.ValueOf(sf.field.DBName).Convert(rv.Type().Key())
+		v := rv.MapIndex(key)
+		if !v.IsValid() || !v.CanInterface() || v.Interface() == nil {
+			continue
+		}
+		plaintext, err := e.open(ctx, sf, v.Interface())
+		if err != nil {
+			return err
+		}
+		if pv := reflect.ValueOf(plaintext); pv.Type().AssignableTo(rv.Type().Elem()) {
+			rv.SetMapIndex(key, pv)
+		}
+	}
+	return nil
+}
+
+// open returns the decrypted value of v, with the type of v. Values other than strings and
+// []byte are kept.
+func (e *Encryptor) open(ctx context.Context, sf secret, v any) (any, error) {
+	ciphertext, ok := bytesOf(v)
+	if !ok {
+		return v, nil
+	}
+	plaintext, err := decrypt(ctx, e.keys, sf.column, string(ciphertext))
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, sf.column)
+	}
+	if _, ok := v.([]byte); ok {
+		return plaintext, nil
+	}
+	return string(plaintext), nil
+}
+
+// rows returns the rows read into rv by a query: addressable structs and maps keyed by strings.
+func rows(rv reflect.Value) []reflect.Value {
+	var out []reflect.Value
+	add := func(rv reflect.Value) {
+		rv = reflect.Indirect(rv)
+		switch {
+		case rv.Kind() == reflect.Struct && rv.CanAddr():
+			out = append(out, rv)
+		case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String && !rv.IsNil():
+			out = append(out, rv)
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			add(rv.Index(i))
+		}
+	default:
+		add(rv)
+	}
+	return out
+}
+
+// entities returns the entities of the model type held by the statement: its reflect value
+// (an entity or a slice of them) and a distinct struct destination of updates.
+func entities(db *gorm.DB) []reflect.Value {
+	stmt := db.Statement
+	var out []reflect.Value
+	add := func(rv reflect.Value) {
+		rv = reflect.Indirect(rv)
+		if rv.Kind() == reflect.Struct && rv.Type() == stmt.Schema.ModelType && rv.CanAddr() {
+			out = append(out, rv)
+		}
+	}
+	switch rv := stmt.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			add(rv.Index(i))
+		}
+	default:
+		add(rv)
+	}
+	if stmt.Dest != stmt.Model {
+		if dv := reflect.ValueOf(stmt.Dest); dv.Kind() == reflect.Pointer && !dv.IsNil() && dv.Elem().Kind() == reflect.Struct {
+			add(dv)
+		}
+	}
+	return out
+}
+
+// bytesOf returns the content of v, a string or []byte (or a type defined on them).
+func bytesOf(v any) ([]byte, bool) {
+	if b, ok := v.([]byte); ok {
+		return b, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.String {
+		return []byte(rv.String()), true
+	}
+	return nil, false
+}
@@ -0,0 +1,323 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+type Patient struct {
+	ID    uint `gorm:"primaryKey"`
+	Name  string
+	Email string `gormr:"encrypted:deterministic"`
+	SSN   string `gormr:"encrypted"`
+	Notes []byte `gormr:"encrypted"`
+}
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func setupEncryption(t *testing.T, keys KeyProvider) (*gorm.DB, *repository.Repository, *Encryptor) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&Patient{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return withEncryption(t, db, keys)
+}
+
+func withEncryption(t *testing.T, db *gorm.DB, keys KeyProvider) (*gorm.DB, *repository.Repository, *Encryptor) {
+	db, err := gorm.Open(sqlite.Dialector{Conn: mustConn(t, db)}, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	e, err := New(Config{Keys: keys})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := db.Use(e); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	repo := repository.New(db)
+	repo.Use(e.Middleware())
+	return db, repo, e
+}
+
+func mustConn(t *testing.T, db *gorm.DB) gorm.ConnPool {
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return sqlDB
+}
+
+func staticKeys(t *testing.T, current string) *StaticKeys {
+	t.Helper()
+	keys, err := NewStaticKeys(current, map[string][]byte{"k1": key1, "k2": key2})
+	if err != nil {
+		t.Fatalf("NewStaticKeys failed: %v", err)
+	}
+	return keys
+}
+
+// raw reads the stored columns of the patient, bypassing the decryption.
+func raw(t *testing.T, db *gorm.DB, id uint) map[string]any {
+	t.Helper()
+	row := map[string]any{}
+	if err := db.Table("patients").Where("id = ?", id).Take(&row).Error; err != nil {
+		t.Fatalf("failed to read row: %v", err)
+	}
+	return row
+}
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	db, repo, _ := setupEncryption(t, staticKeys(t, "k1"))
+	ctx := context.Background()
+
+	p := &Patient{Name: "Ann", Email: "ann@example.com", SSN: "123-45-6789", Notes: []byte("allergic")}
+	if err := repo.Create(ctx, p); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if p.SSN != "123-45-6789" || string(p.Notes) != "allergic" {
+		t.Errorf("expected the entity to keep its plaintext after Create, got %+v", p)
+	}
+	row := raw(t, db, p.ID)
+	for _, col := range []string{"email", "ssn", "notes"} {
+		if v := string(toBytes(row[col])); !strings.HasPrefix(v, "enc:k1:") {
+			t.Errorf("expected %s to be stored encrypted with k1, got %q", col, v)
+		}
+	}
+	if row["name"] != "Ann" {
+		t.Errorf("expected name to be stored in plaintext, got %v", row["name"])
+	}
+
+	var got Patient
+	if err := repo.GetByID(ctx, &Patient{}, p.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Email != p.Email || got.SSN != p.SSN || string(got.Notes) != "allergic" {
+		t.Errorf("expected decrypted values, got %+v", got)
+	}
+
+	// Randomized values differ for equal plaintexts
+	p2 := &Patient{Name: "Bob", Email: "ann@example.com", SSN: "123-45-6789"}
+	if err := repo.Create(ctx, p2); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	row2 := raw(t, db, p2.ID)
+	if row2["ssn"] == row["ssn"] {
+		t.Errorf("expected randomized ciphertexts to differ")
+	}
+	if row2["email"] != row["email"] {
+		t.Errorf("expected deterministic ciphertexts to be equal")
+	}
+}
+
+// PatientDTO reads some columns of Patient.
+type PatientDTO struct {
+	Name  string
+	Email string
+	SSN   string `gorm:"column:ssn"`
+}
+
+func TestEncryption_Reads(t *testing.T) {
+	_, repo, _ := setupEncryption(t, staticKeys(t, "k1"))
+	ctx := context.Background()
+	p := &Patient{Name: "Ann", Email: "ann@example.com", SSN: "123-45-6789", Notes: []byte("allergic")}
+	if err := repo.Create(ctx, p); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	for got, err := range repository.Iterate[Patient](ctx, repo, nil) {
+		if err != nil {
+			t.Fatalf("Iterate failed: %v", err)
+		}
+		if got.Email != p.Email || got.SSN != p.SSN || string(got.Notes) != "allergic" {
+			t.Errorf("expected Iterate to decrypt, got %+v", got)
+		}
+	}
+
+	var dto PatientDTO
+	if err := repo.GetByID(ctx, &Patient{}, p.ID, &dto); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if dto != (PatientDTO{Name: "Ann", Email: p.Email, SSN: p.SSN}) {
+		t.Errorf("expected the DTO to be decrypted, got %+v", dto)
+	}
+	var dtos []PatientDTO
+	if err := repo.GetAll(ctx, &Patient{}, &dtos); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(dtos) != 1 || dtos[0].SSN != p.SSN {
+		t.Errorf("expected the DTOs to be decrypted, got %+v", dtos)
+	}
+
+	var rows []map[string]any
+	if err := repo.Find(ctx, repository.NewSpec(&Patient{}), &rows); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["ssn"] != p.SSN || string(toBytes(rows[0]["notes"])) != "allergic" {
+		t.Errorf("expected the map rows to be decrypted, got %v", rows)
+	}
+}
+
+func TestEncryption_Updates(t *testing.T) {
+	db, repo, _ := setupEncryption(t, staticKeys(t, "k1"))
+	ctx := context.Background()
+	p := &Patient{Name: "Ann", SSN: "111"}
+	if err := repo.Create(ctx, p); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	p.SSN = "222"
+	if err := repo.Update(ctx, p); err != nil || p.SSN != "222" {
+		t.Fatalf("Update failed: %+v (%v)", p, err)
+	}
+	changes := map[string]any{"ssn": "333"}
+	if err := repo.UpdateMap(ctx, &Patient{ID: p.ID}, changes); err != nil {
+		t.Fatalf("UpdateMap failed: %v", err)
+	}
+	if changes["ssn"] != "333" {
+		t.Errorf("expected the caller's map to be left untouched, got %v", changes)
+	}
+	if v := toBytes(raw(t, db, p.ID)["ssn"]); !bytes.HasPrefix(v, []byte("enc:")) {
+		t.Errorf("expected ssn to be stored encrypted, got %q", v)
+	}
+	var got Patient
+	if err := repo.GetByID(ctx, &Patient{}, p.ID, &got); err != nil || got.SSN != "333" {
+		t.Errorf("expected ssn 333, got %+v (%v)", got, err)
+	}
+}
+
+func TestEncryption_Lookup(t *testing.T) {
+	_, repo, e := setupEncryption(t, staticKeys(t, "k1"))
+	ctx := context.Background()
+	for _, email := range []string{"ann@example.com", "bob@example.com"} {
+		if err := repo.Create(ctx, &Patient{Email: email}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var found []Patient
+	if err := repo.GetByField(ctx, &Patient{}, "email", "bob@example.com", &found); err != nil {
+		t.Fatalf("GetByField failed: %v", err)
+	}
+	if len(found) != 1 || found[0].Email != "bob@example.com" {
+		t.Errorf("expected bob, got %+v", found)
+	}
+
+	if err := repo.GetByField(ctx, &Patient{}, "ssn", "1", &found); !errors.Is(err, ErrNotSearchable) {
+		t.Errorf("expected ErrNotSearchable, got %v", err)
+	}
+
+	values, err := e.Lookup(ctx, &Patient{}, "Email", "ann@example.com")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if len(values) != 2 {
+		t.Errorf("expected a ciphertext per key, got %v", values)
+	}
+	n, err := repo.Count(ctx, repository.NewSpec(&Patient{}).Where("email IN ?", values))
+	if err != nil || n != 1 {
+		t.Errorf("expected 1 patient found through Lookup, got %d (%v)", n, err)
+	}
+}
+
+func TestEncryption_KeyRotation(t *testing.T) {
+	db, repo, _ := setupEncryption(t, staticKeys(t, "k1"))
+	ctx := context.Background()
+	old := &Patient{Email: "old@example.com", SSN: "old"}
+	if err := repo.Create(ctx, old); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Rotate: k2 becomes current, k1 stays readable
+	_, rotated, _ := withEncryption(t, db, staticKeys(t, "k2"))
+	p := &Patient{SSN: "new"}
+	if err := rotated.Create(ctx, p); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if v := string(toBytes(raw(t, db, p.ID)["ssn"])); !strings.HasPrefix(v, "enc:k2:") {
+		t.Errorf("expected the new value to use k2, got %q", v)
+	}
+	var all []Patient
+	if err := rotated.GetAll(ctx, &Patient{}, &all); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(all) != 2 || all[0].SSN != "old" || all[1].SSN != "new" {
+		t.Errorf("expected both values to be decrypted, got %+v", all)
+	}
+	var found []Patient
+	if err := rotated.GetByField(ctx, &Patient{}, "email", "old@example.com", &found); err != nil {
+		t.Fatalf("GetByField failed: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != old.ID {
+		t.Errorf("expected the row written with k1 to be found, got %+v", found)
+	}
+
+	// Without the key, reads fail
+	only2, err := NewStaticKeys("k2", map[string][]byte{"k2": key2})
+	if err != nil {
+		t.Fatalf("NewStaticKeys failed: %v", err)
+	}
+	_, missing, _ := withEncryption(t, db, only2)
+	if err := missing.GetAll(ctx, &Patient{}, &all); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestEncryption_Tampering(t *testing.T) {
+	db, repo, _ := setupEncryption(t, staticKeys(t, "k1"))
+	ctx := context.Background()
+	a := &Patient{SSN: "a"}
+	b := &Patient{Email: "b@example.com"}
+	if err := repo.Create(ctx, a); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.Create(ctx, b); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// Moving a ciphertext to another column is detected
+	if err := db.Exec("UPDATE patients SET email = ssn WHERE id = ?", a.ID).Error; err != nil {
+		t.Fatalf("failed to tamper: %v", err)
+	}
+	var got Patient
+	if err := repo.GetByID(ctx, &Patient{}, a.ID, &got); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt, got %v", err)
+	}
+}
+
+func TestNewStaticKeys_Invalid(t *testing.T) {
+	cases := map[string]map[string][]byte{
+		"short key":  {"k1": []byte("short")},
+		"colon":      {"k:1": key1},
+		"no current": {"k2": key2},
+	}
+	for name, keys := range cases {
+		if _, err := NewStaticKeys("k1", keys); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: expected ErrInvalidKey, got %v", name, err)
+		}
+	}
+}
+
+func toBytes(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	}
+	return nil
+}
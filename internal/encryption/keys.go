@@ -0,0 +1,78 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Key is an AES key identified by ID. The ID is stored with every value encrypted with the key,
+// so it must be stable and must not contain ':'.
+type Key struct {
+	ID string
+	// AES-128, AES-192 or AES-256 key (16, 24 or 32 bytes)
+	Secret []byte
+}
+
+// KeyProvider supplies the encryption keys, e.g. from a KMS or a secret manager.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are encrypted with.
+	CurrentKey(ctx context.Context) (Key, error)
+	// Key returns the key with the given ID, to decrypt values encrypted with it.
+	Key(ctx context.Context, id string) (Key, error)
+	// Keys returns every key values may still be encrypted with, the current one included, to
+	// search deterministic values written before a rotation.
+	Keys(ctx context.Context) ([]Key, error)
+}
+
+// StaticKeys is a KeyProvider holding its keys in memory. To rotate keys, add the new key and
+// make it current: values encrypted with the previous keys stay readable.
+type StaticKeys struct {
+	current string
+	keys    map[string]Key
+}
+
+// NewStaticKeys creates a StaticKeys encrypting with the key current among keys (ID -> secret).
+func NewStaticKeys(current string, keys map[string][]byte) (*StaticKeys, error) {
+	s := &StaticKeys{current: current, keys: make(map[string]Key, len(keys))}
+	for id, secret := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: invalid key id %q", ErrInvalidKey, id)
+		}
+		switch len(secret) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("%w: key %q has %d bytes, want 16, 24 or 32", ErrInvalidKey, id, len(secret))
+		}
+		s.keys[id] = Key{ID: id, Secret: secret}
+	}
+	if _, ok := s.keys[current]; !ok {
+		return nil, fmt.Errorf("%w: current key %q not found", ErrInvalidKey, current)
+	}
+	return s, nil
+}
+
+// CurrentKey returns the current key.
+func (s *StaticKeys) CurrentKey(ctx context.Context) (Key, error) {
+	return s.keys[s.current], nil
+}
+
+// Key returns the key with the given ID.
+func (s *StaticKeys) Key(ctx context.Context, id string) (Key, error) {
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return k, nil
+}
+
+// Keys returns every key, ordered by ID.
+func (s *StaticKeys) Keys(ctx context.Context) ([]Key, error) {
+	keys := make([]Key, 0, len(s.keys))
+	for _, id := range slices.Sorted(maps.Keys(s.keys)) {
+		keys = append(keys, s.keys[id])
+	}
+	return keys, nil
+}
@@ -0,0 +1,196 @@
+// Package lock implements distributed locks through the database, for mutual exclusion across instances.
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dialect names reported by GORM dialectors. They match the db.DBDriver values.
+const (
+	dialectPostgres  = "postgres"
+	dialectMySQL     = "mysql"
+	dialectSQLServer = "sqlserver"
+)
+
+const (
+	defaultPollInterval = 100 * time.Millisecond
+	releaseTimeout      = 5 * time.Second
+)
+
+var (
+	// ErrLockHeld is returned by TryLock when another owner holds the lock.
+	ErrLockHeld = errors.New("gormr: lock is held by another owner")
+	// ErrLockLost is returned when renewing or unlocking a lock that was released or whose lease expired.
+	ErrLockLost = errors.New("gormr: lock was released or its lease expired")
+	// ErrInvalidTTL is returned when a lock is requested with a ttl lower than or equal to 0.
+	ErrInvalidTTL = errors.New("gormr: lock ttl must be positive")
+)
+
+// backend acquires locks on one kind of database.
+type backend interface {
+	// tryAcquire takes the lock without waiting; it returns a nil session when the lock is held.
+	tryAcquire(ctx context.Context, name string, ttl time.Duration) (session, error)
+}
+
+// session is a lock held through a backend.
+type session interface {
+	renew(ctx context.Context, ttl time.Duration) error
+	release(ctx context.Context) error
+}
+
+// Config configures a Locker. Zero values select the defaults.
+type Config struct {
+	// Wait between attempts of a blocking Lock (default 100ms)
+	PollInterval time.Duration
+}
+
+// Locker hands out named locks stored in the database:
+//   - Postgres: session-level pg_advisory_lock on a dedicated connection
+//   - MySQL: GET_LOCK on a dedicated connection
+//   - SQL Server: sp_getapplock owned by the session of a dedicated connection
+//   - other dialects (SQLite): rows of the gormr_locks table
+//
+// Session locks are also released by the server when the holding connection drops, so a crashed
+// instance cannot keep a lock forever. Table locks rely on their lease expiring instead.
+type Locker struct {
+	backend backend
+	cfg     Config
+}
+
+// New creates a Locker on db.
+func New(db *gorm.DB, cfg Config) *Locker {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	var b backend
+	switch db.Dialector.Name() {
+	case dialectPostgres:
+		b = &sessionBackend{db: db, queries: postgresQueries}
+	case dialectMySQL:
+		b = &sessionBackend{db: db, queries: mysqlQueries}
+	case dialectSQLServer:
+		b = &sessionBackend{db: db, queries: sqlserverQueries}
+	default:
+		b = &tableBackend{db: db}
+	}
+	return &Locker{backend: b, cfg: cfg}
+}
+
+// TryLock takes the lock called name for a lease of ttl without waiting.
+// It returns ErrLockHeld when another owner holds it.
+func (l *Locker) TryLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	if ttl <= 0 {
+		return nil, ErrInvalidTTL
+	}
+	s, err := l.backend.tryAcquire(ctx, name, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrLockHeld
+	}
+	return newLock(name, s, ttl), nil
+}
+
+// Lock takes the lock called name for a lease of ttl, polling until it is free or ctx is done.
+func (l *Locker) Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	for {
+		lock, err := l.TryLock(ctx, name, ttl)
+		if err != nil && ctx.Err() != nil {
+			// Drivers report a query interrupted by ctx in various ways
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, ErrLockHeld) {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.cfg.PollInterval):
+		}
+	}
+}
+
+// Lock is a held lock. Its lease expires after the ttl unless renewed, at which point it is
+// released and Done is closed.
+type Lock struct {
+	name string
+
+	mu        sync.Mutex
+	sess      session
+	lease     *time.Timer
+	expiresAt time.Time
+	done      chan struct{}
+	released  bool
+}
+
+func newLock(name string, s session, ttl time.Duration) *Lock {
+	l := &Lock{name: name, sess: s, expiresAt: time.Now().Add(ttl), done: make(chan struct{})}
+	l.lease = time.AfterFunc(ttl, l.expire)
+	return l
+}
+
+// Name returns the name of the lock.
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Done is closed once the lock is released, by Unlock or by its lease expiring.
+// Long tasks should stop when it is closed, since another owner may then take the lock.
+func (l *Lock) Done() <-chan struct{} {
+	return l.done
+}
+
+// Renew extends the lease to ttl from now. It returns ErrLockLost if the lock was already released.
+func (l *Lock) Renew(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return ErrLockLost
+	}
+	if err := l.sess.renew(ctx, ttl); err != nil {
+		return err
+	}
+	l.expiresAt = time.Now().Add(ttl)
+	l.lease.Reset(ttl)
+	return nil
+}
+
+// Unlock releases the lock. It returns ErrLockLost if the lock was already released.
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return ErrLockLost
+	}
+	l.lease.Stop()
+	return l.release(ctx)
+}
+
+// expire releases the lock when its lease ends.
+func (l *Lock) expire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// A Renew may have raced with the timer firing
+	if l.released || time.Now().Before(l.expiresAt) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	_ = l.release(ctx)
+}
+
+// release frees the lock; callers hold mu.
+func (l *Lock) release(ctx context.Context) error {
+	l.released = true
+	close(l.done)
+	return l.sess.release(ctx)
+}
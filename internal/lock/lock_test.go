@@ -0,0 +1,153 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupLocker(t *testing.T) *Locker {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return New(db, Config{PollInterval: time.Millisecond})
+}
+
+func TestLocker_TryLock(t *testing.T) {
+	locker := setupLocker(t)
+	ctx := context.Background()
+
+	lock, err := locker.TryLock(ctx, "nightly-report", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+	if _, err := locker.TryLock(ctx, "nightly-report", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("expected ErrLockHeld, got %v", err)
+	}
+	other, err := locker.TryLock(ctx, "cleanup", time.Minute)
+	if err != nil {
+		t.Fatalf("expected an unrelated lock to be free, got %v", err)
+	}
+	defer other.Unlock(ctx)
+
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	select {
+	case <-lock.Done():
+	default:
+		t.Error("expected Done to be closed after Unlock")
+	}
+	if err := lock.Unlock(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("expected ErrLockLost on second Unlock, got %v", err)
+	}
+
+	again, err := locker.TryLock(ctx, "nightly-report", time.Minute)
+	if err != nil {
+		t.Fatalf("expected lock to be free after Unlock, got %v", err)
+	}
+	_ = again.Unlock(ctx)
+}
+
+func TestLocker_InvalidTTL(t *testing.T) {
+	locker := setupLocker(t)
+	if _, err := locker.TryLock(context.Background(), "job", 0); !errors.Is(err, ErrInvalidTTL) {
+		t.Errorf("expected ErrInvalidTTL, got %v", err)
+	}
+}
+
+func TestLocker_LeaseExpires(t *testing.T) {
+	locker := setupLocker(t)
+	ctx := context.Background()
+
+	lock, err := locker.TryLock(ctx, "job", 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+	select {
+	case <-lock.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected Done to be closed when the lease expires")
+	}
+	if err := lock.Renew(ctx, time.Minute); !errors.Is(err, ErrLockLost) {
+		t.Errorf("expected ErrLockLost renewing an expired lock, got %v", err)
+	}
+
+	next, err := locker.TryLock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("expected expired lock to be free, got %v", err)
+	}
+	_ = next.Unlock(ctx)
+}
+
+func TestLocker_Renew(t *testing.T) {
+	locker := setupLocker(t)
+	ctx := context.Background()
+
+	lock, err := locker.TryLock(ctx, "job", 30*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+	if err := lock.Renew(ctx, time.Minute); err != nil {
+		t.Fatalf("Renew failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-lock.Done():
+		t.Fatal("expected renewed lock to be held")
+	default:
+	}
+	if _, err := locker.TryLock(ctx, "job", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Errorf("expected ErrLockHeld while renewed, got %v", err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Errorf("Unlock failed: %v", err)
+	}
+}
+
+func TestLocker_LockWaits(t *testing.T) {
+	locker := setupLocker(t)
+	ctx := context.Background()
+
+	held, err := locker.TryLock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Lock(timeout, "job", time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded while held, got %v", err)
+	}
+
+	time.AfterFunc(20*time.Millisecond, func() { _ = held.Unlock(ctx) })
+	lock, err := locker.Lock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("expected Lock to acquire once released, got %v", err)
+	}
+	_ = lock.Unlock(ctx)
+}
+
+func TestSessionQueries_Key(t *testing.T) {
+	long := string(make([]byte, 100))
+	if got := mysqlQueries.key(long).(string); len(got) > mysqlMaxLockName {
+		t.Errorf("expected MySQL lock name within %d chars, got %d", mysqlMaxLockName, len(got))
+	}
+	if mysqlQueries.key("job") != "job" {
+		t.Error("expected short MySQL lock names to be kept")
+	}
+	if postgresQueries.key("job") != postgresQueries.key("job") || postgresQueries.key("job") == postgresQueries.key("other") {
+		t.Error("expected a stable Postgres key per lock name")
+	}
+}
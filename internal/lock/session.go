@@ -0,0 +1,109 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// mysqlMaxLockName is the longest name accepted by GET_LOCK.
+const mysqlMaxLockName = 64
+
+// sessionQueries are the statements of a session-level lock backend. Both return whether they succeeded.
+type sessionQueries struct {
+	acquire string
+	release string
+	// key maps a lock name to the argument of the statements
+	key func(name string) any
+}
+
+var postgresQueries = sessionQueries{
+	acquire: "SELECT pg_try_advisory_lock($1)",
+	release: "SELECT pg_advisory_unlock($1)",
+	key: func(name string) any {
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		return int64(h.Sum64())
+	},
+}
+
+var mysqlQueries = sessionQueries{
+	acquire: "SELECT GET_LOCK(?, 0)",
+	release: "SELECT RELEASE_LOCK(?)",
+	key: func(name string) any {
+		if len(name) <= mysqlMaxLockName {
+			return name
+		}
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		return fmt.Sprintf("gormr:%x", h.Sum64())
+	},
+}
+
+var sqlserverQueries = sessionQueries{
+	acquire: "DECLARE @r int; " +
+		"EXEC @r = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0; " +
+		"SELECT CAST(CASE WHEN @r >= 0 THEN 1 ELSE 0 END AS bit)",
+	release: "DECLARE @r int; " +
+		"EXEC @r = sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'; " +
+		"SELECT CAST(CASE WHEN @r >= 0 THEN 1 ELSE 0 END AS bit)",
+	key: func(name string) any {
+		return name
+	},
+}
+
+// sessionBackend holds each lock on a dedicated connection taken from the pool, so the
+// server releases it if the connection drops.
+type sessionBackend struct {
+	db      *gorm.DB
+	queries sessionQueries
+}
+
+func (b *sessionBackend) tryAcquire(ctx context.Context, name string, _ time.Duration) (session, error) {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := b.queries.key(name)
+	var ok sql.NullBool
+	if err := conn.QueryRowContext(ctx, b.queries.acquire, key).Scan(&ok); err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+	if !ok.Bool {
+		return nil, conn.Close()
+	}
+	return &connSession{conn: conn, key: key, releaseQuery: b.queries.release}, nil
+}
+
+// connSession is a lock held by the session of conn.
+type connSession struct {
+	conn         *sql.Conn
+	key          any
+	releaseQuery string
+}
+
+// renew checks the connection holding the lock is still alive; the lease itself is local.
+func (s *connSession) renew(ctx context.Context, _ time.Duration) error {
+	if err := s.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrLockLost, err)
+	}
+	return nil
+}
+
+func (s *connSession) release(ctx context.Context) error {
+	var ok sql.NullBool
+	err := s.conn.QueryRowContext(ctx, s.releaseQuery, s.key).Scan(&ok)
+	if err == nil && !ok.Bool {
+		err = ErrLockLost
+	}
+	return errors.Join(err, s.conn.Close())
+}
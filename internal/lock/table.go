@@ -0,0 +1,106 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockRow is a lock held through the gormr_locks table.
+type lockRow struct {
+	Name      string    `gorm:"primaryKey;size:191"`
+	Owner     string    `gorm:"size:32;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+// TableName returns the table storing the locks.
+func (lockRow) TableName() string {
+	return "gormr_locks"
+}
+
+// tableBackend stores each lock as a row with an owner token and a lease expiry. It works on
+// any database, SQLite included, and creates its table on first use.
+type tableBackend struct {
+	db *gorm.DB
+
+	mu       sync.Mutex
+	migrated bool
+}
+
+func (b *tableBackend) tryAcquire(ctx context.Context, name string, ttl time.Duration) (session, error) {
+	if err := b.migrate(ctx); err != nil {
+		return nil, err
+	}
+	db := b.db.WithContext(ctx)
+	now := time.Now().UTC()
+	// Take over a lock whose owner let its lease expire
+	if err := db.Where("name = ? AND expires_at < ?", name, now).Delete(&lockRow{}).Error; err != nil {
+		return nil, err
+	}
+	row := &lockRow{Name: name, Owner: newOwner(), ExpiresAt: now.Add(ttl)}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, res.Error
+	}
+	return &rowSession{db: b.db, name: name, owner: row.Owner}, nil
+}
+
+// migrate creates the gormr_locks table once.
+func (b *tableBackend) migrate(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.migrated {
+		return nil
+	}
+	if err := b.db.WithContext(ctx).AutoMigrate(&lockRow{}); err != nil {
+		return err
+	}
+	b.migrated = true
+	return nil
+}
+
+// rowSession is a lock held through the row called name, as long as owner matches.
+type rowSession struct {
+	db    *gorm.DB
+	name  string
+	owner string
+}
+
+func (s *rowSession) renew(ctx context.Context, ttl time.Duration) error {
+	res := s.owned(ctx).Update("expires_at", time.Now().UTC().Add(ttl))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (s *rowSession) release(ctx context.Context) error {
+	res := s.owned(ctx).Delete(&lockRow{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// owned scopes a query to the row while it belongs to the session.
+func (s *rowSession) owned(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&lockRow{}).Where("name = ? AND owner = ?", s.name, s.owner)
+}
+
+// newOwner returns a random token identifying a lock holder.
+func newOwner() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
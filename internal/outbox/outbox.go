@@ -0,0 +1,103 @@
+// Package outbox implements the transactional outbox pattern: events are stored in the same
+// transaction as the data they describe, then relayed to a message broker.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// ErrEventNotDead is returned by Requeue for events that are not dead-lettered.
+var ErrEventNotDead = errors.New("gormr: outbox event is not dead-lettered")
+
+// Event is a message stored in the gormr_outbox table until it is published.
+type Event struct {
+	ID uint64 `gorm:"primaryKey"`
+	// Events sharing a key are published in the order they were appended
+	AggregateKey string `gorm:"size:191;not null;index"`
+	Topic        string `gorm:"size:191;not null"`
+	Payload      []byte
+	Attempts     int
+	// Time before which a failed event is not retried
+	NextAttemptAt time.Time `gorm:"not null"`
+	LastError     string
+	DeliveredAt   *time.Time `gorm:"index"`
+	// Time the event was dead-lettered after using all its attempts
+	DeadAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// TableName returns the table storing the events.
+func (Event) TableName() string {
+	return "gormr_outbox"
+}
+
+// Outbox appends events to the gormr_outbox table.
+type Outbox struct {
+	repo *repository.Repository
+}
+
+// New creates an Outbox on top of repo.
+func New(repo *repository.Repository) *Outbox {
+	return &Outbox{repo: repo}
+}
+
+// In returns an Outbox appending through txRepo, for use inside Repository.Transaction.
+func (o *Outbox) In(txRepo *repository.Repository) *Outbox {
+	return &Outbox{repo: txRepo}
+}
+
+// Migrate creates or updates the gormr_outbox table.
+func (o *Outbox) Migrate(ctx context.Context) error {
+	return o.repo.AutoMigrate(ctx, &Event{})
+}
+
+// Append stores events in the active transaction, so they are only published if it commits.
+// The transaction is the one the Outbox is bound to (see In) or the one carried by ctx
+// (see Repository.RunInTransaction). Append returns ErrNotInTransaction outside a transaction.
+func (o *Outbox) Append(ctx context.Context, events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	for _, e := range events {
+		e.NextAttemptAt = now
+	}
+	return o.repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		return o.repo.Create(ctx, events)
+	}, repository.WithPropagation(repository.PropagationMandatory))
+}
+
+// DeadLetters returns the events dead-lettered by a relay after using all their attempts, oldest first.
+func (o *Outbox) DeadLetters(ctx context.Context) ([]Event, error) {
+	var events []Event
+	err := o.repo.Find(ctx, repository.NewSpec(&Event{}).Where("dead_at IS NOT NULL").OrderBy("id"), &events)
+	return events, err
+}
+
+// Requeue makes a dead-lettered event pending again with a fresh set of attempts. Later events of
+// its key may have been published meanwhile, so it is published out of order.
+func (o *Outbox) Requeue(ctx context.Context, e *Event) error {
+	spec := repository.NewSpec(&Event{}).Eq("id", e.ID).Where("dead_at IS NOT NULL")
+	n, err := o.repo.UpdateWhere(ctx, spec, map[string]any{
+		"dead_at":         nil,
+		"attempts":        0,
+		"next_attempt_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrEventNotDead
+	}
+	e.DeadAt, e.Attempts = nil, 0
+	return nil
+}
+
+// Purge deletes the events delivered before olderThan and returns how many were deleted.
+func (o *Outbox) Purge(ctx context.Context, olderThan time.Time) (int64, error) {
+	return o.repo.DeleteWhere(ctx, repository.NewSpec(&Event{}).Where("delivered_at < ?", olderThan.UTC()))
+}
@@ -0,0 +1,279 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+type Order struct {
+	ID    uint `gorm:"primaryKey"`
+	Total int
+}
+
+// recorder is a Publisher recording the events it receives and failing for the keys in fail.
+type recorder struct {
+	published []string
+	fail      map[string]bool
+}
+
+func (p *recorder) Publish(_ context.Context, e *Event) error {
+	if p.fail[e.AggregateKey] {
+		return fmt.Errorf("broker rejected %s", e.Payload)
+	}
+	p.published = append(p.published, string(e.Payload))
+	return nil
+}
+
+func setupOutbox(t *testing.T) (*repository.Repository, *Outbox) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	// Every :memory: connection is a distinct database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&Order{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	repo := repository.New(db)
+	o := New(repo)
+	if err := o.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate outbox: %v", err)
+	}
+	return repo, o
+}
+
+func event(key, payload string) *Event {
+	return &Event{AggregateKey: key, Topic: "orders", Payload: []byte(payload)}
+}
+
+func appendEvents(t *testing.T, repo *repository.Repository, o *Outbox, events ...*Event) {
+	t.Helper()
+	err := repo.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		return o.Append(ctx, events...)
+	})
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+}
+
+func TestOutbox_AppendRequiresTransaction(t *testing.T) {
+	_, o := setupOutbox(t)
+	if err := o.Append(context.Background(), event("order-1", "created")); !errors.Is(err, repository.ErrNotInTransaction) {
+		t.Errorf("expected ErrNotInTransaction, got %v", err)
+	}
+}
+
+func TestOutbox_AppendFollowsTransaction(t *testing.T) {
+	repo, o := setupOutbox(t)
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+
+	err := repo.Transaction(ctx, func(txRepo *repository.Repository) error {
+		order := &Order{Total: 10}
+		if err := txRepo.Create(ctx, order); err != nil {
+			return err
+		}
+		return o.In(txRepo).Append(ctx, event("order-1", "created"))
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	err = repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, &Order{Total: 20}); err != nil {
+			return err
+		}
+		if err := o.Append(ctx, event("order-2", "created")); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+
+	pub := &recorder{}
+	n, err := o.NewRelay(pub, RelayConfig{}).RelayOnce(ctx)
+	if err != nil {
+		t.Fatalf("RelayOnce failed: %v", err)
+	}
+	if n != 1 || !slices.Equal(pub.published, []string{"created"}) {
+		t.Errorf("expected only the committed event published, got %d %v", n, pub.published)
+	}
+}
+
+func TestRelay_OrderPerKey(t *testing.T) {
+	repo, o := setupOutbox(t)
+	ctx := context.Background()
+	appendEvents(t, repo, o,
+		event("order-1", "1-created"),
+		event("order-2", "2-created"),
+		event("order-1", "1-paid"),
+		event("order-1", "1-shipped"),
+	)
+
+	pub := &recorder{}
+	relay := o.NewRelay(pub, RelayConfig{})
+	total := 0
+	for range 5 {
+		n, err := relay.RelayOnce(ctx)
+		if err != nil {
+			t.Fatalf("RelayOnce failed: %v", err)
+		}
+		total += n
+	}
+
+	want := []string{"1-created", "2-created", "1-paid", "1-shipped"}
+	if total != len(want) || !slices.Equal(pub.published, want) {
+		t.Errorf("expected %v, got %v", want, pub.published)
+	}
+}
+
+func TestRelay_RetryBlocksKey(t *testing.T) {
+	repo, o := setupOutbox(t)
+	ctx := context.Background()
+	appendEvents(t, repo, o,
+		event("order-1", "1-created"),
+		event("order-1", "1-paid"),
+		event("order-2", "2-created"),
+	)
+
+	pub := &recorder{fail: map[string]bool{"order-1": true}}
+	var errs []error
+	relay := o.NewRelay(pub, RelayConfig{
+		Backoff: func(int) time.Duration { return 0 },
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	for range 3 {
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("RelayOnce failed: %v", err)
+		}
+	}
+	if !slices.Equal(pub.published, []string{"2-created"}) {
+		t.Fatalf("expected only the other key published, got %v", pub.published)
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected a reported error per failed attempt, got %v", errs)
+	}
+
+	var head Event
+	if err := repo.GetByField(ctx, &Event{}, "payload", []byte("1-created"), &head); err != nil {
+		t.Fatalf("GetByField failed: %v", err)
+	}
+	if head.Attempts != 3 || head.LastError == "" || head.DeliveredAt != nil {
+		t.Errorf("expected failed head with 3 attempts, got %+v", head)
+	}
+
+	pub.fail = nil
+	for range 2 {
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("RelayOnce failed: %v", err)
+		}
+	}
+	want := []string{"2-created", "1-created", "1-paid"}
+	if !slices.Equal(pub.published, want) {
+		t.Errorf("expected %v, got %v", want, pub.published)
+	}
+}
+
+func TestRelay_MaxAttemptsDeadLetters(t *testing.T) {
+	repo, o := setupOutbox(t)
+	ctx := context.Background()
+	appendEvents(t, repo, o, event("order-1", "1-created"), event("order-1", "1-paid"))
+
+	pub := &recorder{fail: map[string]bool{"order-1": true}}
+	relay := o.NewRelay(pub, RelayConfig{MaxAttempts: 2, Backoff: func(int) time.Duration { return 0 }})
+	for range 2 {
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("RelayOnce failed: %v", err)
+		}
+	}
+	dead, err := o.DeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("DeadLetters failed: %v", err)
+	}
+	if len(dead) != 1 || string(dead[0].Payload) != "1-created" || dead[0].Attempts != 2 || dead[0].DeadAt == nil {
+		t.Fatalf("expected the head to be dead-lettered, got %+v", dead)
+	}
+
+	// The key is no longer blocked
+	pub.fail = nil
+	if _, err := relay.RelayOnce(ctx); err != nil {
+		t.Fatalf("RelayOnce failed: %v", err)
+	}
+	if !slices.Equal(pub.published, []string{"1-paid"}) {
+		t.Fatalf("expected the next event of the key published, got %v", pub.published)
+	}
+
+	if err := o.Requeue(ctx, &dead[0]); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+	if err := o.Requeue(ctx, &dead[0]); !errors.Is(err, ErrEventNotDead) {
+		t.Errorf("expected ErrEventNotDead, got %v", err)
+	}
+	if _, err := relay.RelayOnce(ctx); err != nil {
+		t.Fatalf("RelayOnce failed: %v", err)
+	}
+	if !slices.Equal(pub.published, []string{"1-paid", "1-created"}) {
+		t.Errorf("expected the requeued event published, got %v", pub.published)
+	}
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	repo, o := setupOutbox(t)
+	ctx := context.Background()
+	appendEvents(t, repo, o, event("order-1", "created"))
+
+	pub := &recorder{fail: map[string]bool{"order-1": true}}
+	relay := o.NewRelay(pub, RelayConfig{Backoff: func(int) time.Duration { return time.Hour }})
+	if _, err := relay.RelayOnce(ctx); err != nil {
+		t.Fatalf("RelayOnce failed: %v", err)
+	}
+
+	pub.fail = nil
+	n, err := relay.RelayOnce(ctx)
+	if err != nil {
+		t.Fatalf("RelayOnce failed: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected the failed event to wait for its backoff, got %d delivered", n)
+	}
+}
+
+func TestRelay_RunAndPurge(t *testing.T) {
+	repo, o := setupOutbox(t)
+	appendEvents(t, repo, o, event("order-1", "created"), event("order-2", "created"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pub := PublisherFunc(func(_ context.Context, e *Event) error {
+		if e.AggregateKey == "order-2" {
+			cancel()
+		}
+		return nil
+	})
+	if err := o.NewRelay(pub, RelayConfig{PollInterval: time.Millisecond}).Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	n, err := o.Purge(context.Background(), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 delivered events purged, got %d", n)
+	}
+}
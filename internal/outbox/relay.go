@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// Publisher delivers events to a message broker. Publish must be idempotent on the consumer side
+// (or the consumers must deduplicate on Event.ID): delivery is at least once.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc func(ctx context.Context, event *Event) error
+
+// Publish calls f(ctx, event).
+func (f PublisherFunc) Publish(ctx context.Context, event *Event) error {
+	return f(ctx, event)
+}
+
+// RelayConfig configures a Relay. Zero values select the defaults.
+type RelayConfig struct {
+	// Maximum number of events published per poll (default 100)
+	BatchSize int
+	// Wait between polls (default 1s)
+	PollInterval time.Duration
+	// Delay before retrying an event that failed to publish (default exponential from 1s to 5m)
+	Backoff repository.Backoff
+	// Number of publication attempts after which an event is dead-lettered (default 20), so it
+	// stops blocking the later events of its key
+	MaxAttempts int
+	// Called with errors that do not stop Run: failed polls and publications
+	OnError func(error)
+}
+
+// Relay polls the outbox and publishes the pending events.
+type Relay struct {
+	repo *repository.Repository
+	pub  Publisher
+	cfg  RelayConfig
+}
+
+// NewRelay creates a Relay publishing the events of the outbox through pub.
+func (o *Outbox) NewRelay(pub Publisher, cfg RelayConfig) *Relay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 20
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = repository.ExponentialBackoff(time.Second, 5*time.Minute)
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(error) {}
+	}
+	return &Relay{repo: o.repo, pub: pub, cfg: cfg}
+}
+
+// Run publishes pending events every poll interval until ctx is cancelled. A batch in flight when
+// ctx is cancelled is finished first, so its published events are marked delivered.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		if _, err := r.RelayOnce(context.WithoutCancel(ctx)); err != nil {
+			r.cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// RelayOnce publishes one batch of pending events and returns how many were delivered.
+//
+// Only the oldest pending event of each aggregate key is eligible, so events of a key are published
+// in order: when one fails it is retried after the backoff and the later events of its key wait,
+// until it used MaxAttempts and is dead-lettered (see Outbox.DeadLetters).
+// Events are read with FOR UPDATE SKIP LOCKED inside a transaction kept open while publishing, so
+// several relays can run against Postgres, MySQL or SQL Server without reordering a key. SQLite has
+// no row locks: run a single relay there.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	delivered := 0
+	err := r.repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		events, err := r.pending(ctx)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			ok, err := r.publish(ctx, &events[i])
+			if err != nil {
+				return err
+			}
+			if ok {
+				delivered++
+			}
+		}
+		return nil
+	}, repository.WithPropagation(repository.PropagationRequiresNew))
+	return delivered, err
+}
+
+// pending reads the due heads of the aggregate keys, oldest first.
+func (r *Relay) pending(ctx context.Context) ([]Event, error) {
+	spec := repository.NewSpec(&Event{}).
+		Where("id IN (SELECT MIN(id) FROM gormr_outbox WHERE delivered_at IS NULL AND dead_at IS NULL GROUP BY aggregate_key)").
+		// Repeated: under READ COMMITTED the subquery is not re-evaluated once a lock wait ends
+		Where("delivered_at IS NULL AND dead_at IS NULL").
+		Where("next_attempt_at <= ?", time.Now().UTC()).
+		OrderBy("id").
+		Limit(r.cfg.BatchSize)
+	var events []Event
+	err := r.repo.Find(ctx, spec, &events, repository.WithLock(repository.ForUpdate|repository.SkipLocked))
+	return events, err
+}
+
+// publish publishes e and records the outcome. A failed publication is reported to OnError and
+// scheduled for a retry, or dead-lettered on its last attempt; only failing to record the outcome
+// returns an error.
+func (r *Relay) publish(ctx context.Context, e *Event) (bool, error) {
+	if err := r.pub.Publish(ctx, e); err != nil {
+		r.cfg.OnError(err)
+		now := time.Now().UTC()
+		changes := map[string]any{
+			"attempts":   e.Attempts + 1,
+			"last_error": err.Error(),
+		}
+		if e.Attempts+1 >= r.cfg.MaxAttempts {
+			changes["dead_at"] = now
+		} else {
+			changes["next_attempt_at"] = now.Add(r.cfg.Backoff(e.Attempts + 1))
+		}
+		_, updErr := r.repo.UpdateWhere(ctx, repository.NewSpec(&Event{}).Eq("id", e.ID), changes)
+		return false, updErr
+	}
+	_, err := r.repo.UpdateWhere(ctx, repository.NewSpec(&Event{}).Eq("id", e.ID), map[string]any{
+		"attempts":     e.Attempts + 1,
+		"delivered_at": time.Now().UTC(),
+	})
+	return err == nil, err
+}
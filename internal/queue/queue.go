@@ -0,0 +1,302 @@
+// Package queue implements a database-backed job queue on top of the gormr repository.
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// Defaults applied to a zero Config.
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultMaxAttempts       = 5
+	defaultBackoffBase       = time.Second
+	defaultBackoffMax        = 10 * time.Minute
+
+	// errTimedOut is the last error of jobs dead-lettered after their last attempt timed out
+	errTimedOut = "gormr: visibility timeout expired on the last attempt"
+)
+
+// ErrJobLost is returned when finishing a job whose claim expired and was taken by another worker.
+var ErrJobLost = errors.New("gormr: job claim expired or was taken by another worker")
+
+// Status is the state of a job.
+type Status string
+
+// Job statuses.
+const (
+	StatusReady   Status = "ready"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusDead    Status = "dead"
+)
+
+// Job is a unit of work stored in the gormr_jobs table.
+type Job struct {
+	ID          uint64    `gorm:"primaryKey"`
+	Queue       string    `gorm:"size:191;not null;index:idx_gormr_jobs_poll,priority:1"`
+	Status      Status    `gorm:"size:16;not null;index:idx_gormr_jobs_poll,priority:2"`
+	RunAt       time.Time `gorm:"not null;index:idx_gormr_jobs_poll,priority:3"`
+	Payload     []byte
+	Attempts    int
+	MaxAttempts int
+	// End of the visibility timeout of a running job
+	LockedUntil *time.Time
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TableName returns the table storing the jobs.
+func (Job) TableName() string {
+	return "gormr_jobs"
+}
+
+// Config configures a Queue. Zero values select the defaults.
+type Config struct {
+	// How long a dequeued job stays invisible to other workers (default 30s)
+	VisibilityTimeout time.Duration
+	// Number of attempts before a job is dead-lettered (default 5)
+	MaxAttempts int
+	// Delay before retrying a failed job (default exponential from 1s to 10m)
+	Backoff repository.Backoff
+}
+
+// Queue is a named job queue stored in the gormr_jobs table.
+type Queue struct {
+	repo *repository.Repository
+	name string
+	cfg  Config
+}
+
+// New creates the queue named name on top of repo.
+func New(repo *repository.Repository, name string, cfg Config) *Queue {
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = repository.ExponentialBackoff(defaultBackoffBase, defaultBackoffMax)
+	}
+	return &Queue{repo: repo, name: name, cfg: cfg}
+}
+
+// Name returns the queue name.
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Migrate creates or updates the gormr_jobs table.
+func (q *Queue) Migrate(ctx context.Context) error {
+	return q.repo.AutoMigrate(ctx, &Job{})
+}
+
+// EnqueueOption customises a job added by Enqueue.
+type EnqueueOption func(*Job)
+
+// Delay makes the job available only after d.
+func Delay(d time.Duration) EnqueueOption {
+	return func(j *Job) {
+		j.RunAt = j.RunAt.Add(d)
+	}
+}
+
+// MaxAttempts overrides the queue's number of attempts for the job.
+func MaxAttempts(n int) EnqueueOption {
+	return func(j *Job) {
+		j.MaxAttempts = n
+	}
+}
+
+// Enqueue adds a job with the given payload. It joins the transaction active in ctx, if any,
+// so the job is only visible once the data it refers to is committed.
+func (q *Queue) Enqueue(ctx context.Context, payload []byte, opts ...EnqueueOption) (*Job, error) {
+	job := &Job{
+		Queue:       q.name,
+		Status:      StatusReady,
+		RunAt:       now(),
+		Payload:     payload,
+		MaxAttempts: q.cfg.MaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	if err := q.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Dequeue claims the next available job for the visibility timeout: a ready job whose run time
+// has come, or a running job whose visibility timeout expired. It returns nil when none is available.
+// Expired jobs that used all their attempts are moved to the dead-letter state instead.
+//
+// The candidate is read with FOR UPDATE SKIP LOCKED (READPAST on SQL Server) so concurrent
+// workers skip each other's rows. SQLite has no row locks: there the claim is a conditional
+// UPDATE on the attempt counter, and a worker losing the race simply tries the next candidate.
+func (q *Queue) Dequeue(ctx context.Context) (*Job, error) {
+	const maxClaims = 3
+	var claimed *Job
+	err := q.repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		for claims := 0; claims < maxClaims; {
+			job, err := q.next(ctx)
+			if err != nil || job == nil {
+				return err
+			}
+			if job.Status == StatusRunning && job.Attempts >= job.MaxAttempts {
+				// Its last attempt timed out: the worker crashed or hung
+				if err := q.bury(ctx, job); err != nil {
+					return err
+				}
+				continue
+			}
+			claims++
+			ok, err := q.claim(ctx, job)
+			if err != nil {
+				return err
+			}
+			if ok {
+				claimed = job
+				return nil
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// next reads the next available job, skipping rows locked by other workers.
+func (q *Queue) next(ctx context.Context) (*Job, error) {
+	t := now()
+	spec := repository.NewSpec(&Job{}).
+		Eq("queue", q.name).
+		Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)", StatusReady, t, StatusRunning, t).
+		OrderBy("run_at").
+		OrderBy("id").
+		Limit(1)
+	var jobs []Job
+	if err := q.repo.Find(ctx, spec, &jobs, repository.WithLock(repository.ForUpdate|repository.SkipLocked)); err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// claim marks job as running if no other worker claimed it since it was read.
+func (q *Queue) claim(ctx context.Context, job *Job) (bool, error) {
+	lockedUntil := now().Add(q.cfg.VisibilityTimeout)
+	n, err := q.repo.UpdateWhere(ctx, q.owned(job, job.Status), map[string]any{
+		"status":       StatusRunning,
+		"attempts":     gorm.Expr("attempts + 1"),
+		"locked_until": lockedUntil,
+	})
+	if err != nil || n == 0 {
+		return false, err
+	}
+	job.Status = StatusRunning
+	job.Attempts++
+	job.LockedUntil = &lockedUntil
+	return true, nil
+}
+
+// bury moves a job whose last attempt timed out to the dead-letter state.
+func (q *Queue) bury(ctx context.Context, job *Job) error {
+	_, err := q.repo.UpdateWhere(ctx, q.owned(job, StatusRunning), map[string]any{
+		"status":       StatusDead,
+		"locked_until": nil,
+		"last_error":   errTimedOut,
+	})
+	return err
+}
+
+// Complete marks a dequeued job as done.
+func (q *Queue) Complete(ctx context.Context, job *Job) error {
+	return q.finish(ctx, job, map[string]any{
+		"status":       StatusDone,
+		"locked_until": nil,
+	})
+}
+
+// Fail records the failure of a dequeued job. The job is retried after the queue backoff,
+// or moved to the dead-letter state once it used all its attempts.
+func (q *Queue) Fail(ctx context.Context, job *Job, cause error) error {
+	changes := map[string]any{
+		"status":       StatusReady,
+		"locked_until": nil,
+		"last_error":   cause.Error(),
+	}
+	if job.Attempts >= job.MaxAttempts {
+		changes["status"] = StatusDead
+	} else {
+		changes["run_at"] = now().Add(q.cfg.Backoff(job.Attempts))
+	}
+	return q.finish(ctx, job, changes)
+}
+
+// finish applies changes to a job still owned by the caller.
+func (q *Queue) finish(ctx context.Context, job *Job, changes map[string]any) error {
+	n, err := q.repo.UpdateWhere(ctx, q.owned(job, StatusRunning), changes)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrJobLost
+	}
+	job.Status = changes["status"].(Status)
+	job.LockedUntil = nil
+	return nil
+}
+
+// owned matches job as long as it is still in status and was not claimed again.
+func (q *Queue) owned(job *Job, status Status) *repository.Spec {
+	return repository.NewSpec(&Job{}).
+		Eq("id", job.ID).
+		Eq("status", status).
+		Eq("attempts", job.Attempts)
+}
+
+// DeadLetters returns the jobs of the queue that used all their attempts, oldest first.
+func (q *Queue) DeadLetters(ctx context.Context) ([]Job, error) {
+	spec := repository.NewSpec(&Job{}).
+		Eq("queue", q.name).
+		Eq("status", StatusDead).
+		OrderBy("id")
+	var jobs []Job
+	err := q.repo.Find(ctx, spec, &jobs)
+	return jobs, err
+}
+
+// Requeue makes a dead-lettered job available again with a fresh set of attempts.
+func (q *Queue) Requeue(ctx context.Context, job *Job) error {
+	spec := repository.NewSpec(&Job{}).
+		Eq("id", job.ID).
+		Eq("status", StatusDead)
+	n, err := q.repo.UpdateWhere(ctx, spec, map[string]any{
+		"status":   StatusReady,
+		"attempts": 0,
+		"run_at":   now(),
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	job.Status = StatusReady
+	job.Attempts = 0
+	return nil
+}
+
+// now returns the current time in UTC, so times compare correctly on SQLite where they are stored as text.
+func now() time.Time {
+	return time.Now().UTC()
+}
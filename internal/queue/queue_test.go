@@ -0,0 +1,304 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+var noBackoff = func(int) time.Duration { return 0 }
+
+func setupQueue(t *testing.T, cfg Config) *Queue {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	// Every :memory: connection is a distinct database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	q := New(repository.New(db), "emails", cfg)
+	if err := q.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return q
+}
+
+func mustEnqueue(t *testing.T, q *Queue, payload string, opts ...EnqueueOption) *Job {
+	t.Helper()
+	job, err := q.Enqueue(context.Background(), []byte(payload), opts...)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	return job
+}
+
+func TestQueue_DequeueComplete(t *testing.T) {
+	q := setupQueue(t, Config{})
+	ctx := context.Background()
+	first := mustEnqueue(t, q, "first")
+	mustEnqueue(t, q, "second")
+	mustEnqueue(t, q, "later", Delay(time.Hour))
+	if _, err := New(q.repo, "other", Config{}).Enqueue(ctx, []byte("other queue")); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	job, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if job == nil || job.ID != first.ID || job.Status != StatusRunning || job.Attempts != 1 {
+		t.Fatalf("expected first job running on attempt 1, got %+v", job)
+	}
+	if err := q.Complete(ctx, job); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	second, err := q.Dequeue(ctx)
+	if err != nil || second == nil || string(second.Payload) != "second" {
+		t.Fatalf("expected second job, got %+v (err %v)", second, err)
+	}
+	none, err := q.Dequeue(ctx)
+	if err != nil || none != nil {
+		t.Fatalf("expected no available job, got %+v (err %v)", none, err)
+	}
+}
+
+func TestQueue_VisibilityTimeout(t *testing.T) {
+	q := setupQueue(t, Config{VisibilityTimeout: 10 * time.Millisecond})
+	ctx := context.Background()
+	mustEnqueue(t, q, "slow")
+
+	abandoned, err := q.Dequeue(ctx)
+	if err != nil || abandoned == nil {
+		t.Fatalf("expected a job, got %+v (err %v)", abandoned, err)
+	}
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Fatalf("expected running job to be invisible, got %+v", job)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	job, err := q.Dequeue(ctx)
+	if err != nil || job == nil || job.ID != abandoned.ID || job.Attempts != 2 {
+		t.Fatalf("expected expired job on attempt 2, got %+v (err %v)", job, err)
+	}
+	if err := q.Complete(ctx, abandoned); !errors.Is(err, ErrJobLost) {
+		t.Errorf("expected ErrJobLost for the expired claim, got %v", err)
+	}
+	if err := q.Complete(ctx, job); err != nil {
+		t.Errorf("Complete failed: %v", err)
+	}
+}
+
+func TestQueue_VisibilityTimeoutDeadLetters(t *testing.T) {
+	q := setupQueue(t, Config{VisibilityTimeout: 10 * time.Millisecond})
+	ctx := context.Background()
+	hung := mustEnqueue(t, q, "hangs", MaxAttempts(2))
+	next := mustEnqueue(t, q, "next")
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		job, err := q.Dequeue(ctx)
+		if err != nil || job == nil || job.ID != hung.ID || job.Attempts != attempt {
+			t.Fatalf("attempt %d: expected the hung job, got %+v (err %v)", attempt, job, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	job, err := q.Dequeue(ctx)
+	if err != nil || job == nil || job.ID != next.ID {
+		t.Fatalf("expected the next job, got %+v (err %v)", job, err)
+	}
+	dead, err := q.DeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("DeadLetters failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != hung.ID || dead[0].Attempts != 2 || dead[0].LastError != errTimedOut {
+		t.Fatalf("expected the hung job to be dead-lettered, got %+v", dead)
+	}
+}
+
+func TestQueue_FailRetriesThenDeadLetters(t *testing.T) {
+	q := setupQueue(t, Config{Backoff: noBackoff})
+	ctx := context.Background()
+	mustEnqueue(t, q, "flaky", MaxAttempts(2))
+	cause := errors.New("smtp unavailable")
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		job, err := q.Dequeue(ctx)
+		if err != nil || job == nil || job.Attempts != attempt {
+			t.Fatalf("attempt %d: expected job, got %+v (err %v)", attempt, job, err)
+		}
+		if err := q.Fail(ctx, job, cause); err != nil {
+			t.Fatalf("Fail failed: %v", err)
+		}
+	}
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Fatalf("expected dead job not to be dequeued, got %+v", job)
+	}
+
+	dead, err := q.DeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("DeadLetters failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0].LastError != cause.Error() || dead[0].Attempts != 2 {
+		t.Fatalf("expected one dead job with the last error, got %+v", dead)
+	}
+
+	if err := q.Requeue(ctx, &dead[0]); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+	job, err := q.Dequeue(ctx)
+	if err != nil || job == nil || job.Attempts != 1 {
+		t.Fatalf("expected requeued job on attempt 1, got %+v (err %v)", job, err)
+	}
+}
+
+func TestQueue_FailAppliesBackoff(t *testing.T) {
+	q := setupQueue(t, Config{Backoff: func(int) time.Duration { return time.Hour }})
+	ctx := context.Background()
+	mustEnqueue(t, q, "retry later")
+
+	job, _ := q.Dequeue(ctx)
+	if err := q.Fail(ctx, job, errors.New("boom")); err != nil {
+		t.Fatalf("Fail failed: %v", err)
+	}
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Errorf("expected job to wait for the backoff, got %+v", job)
+	}
+}
+
+func TestQueue_EnqueueJoinsTransaction(t *testing.T) {
+	q := setupQueue(t, Config{})
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+	mustEnqueue(t, q, "committed")
+
+	err := q.repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if _, err := q.Enqueue(ctx, []byte("rolled back")); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	n, err := q.repo.Count(ctx, repository.NewSpec(&Job{}))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected only the job enqueued outside the transaction, got %d", n)
+	}
+}
+
+func TestQueue_Work(t *testing.T) {
+	q := setupQueue(t, Config{Backoff: noBackoff})
+	const jobs = 10
+	for range jobs {
+		mustEnqueue(t, q, "work")
+	}
+	poison := mustEnqueue(t, q, "poison", MaxAttempts(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	seen := map[uint64]int{}
+	var done atomic.Int32
+	handler := func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		seen[job.ID]++
+		mu.Unlock()
+		if done.Add(1) == jobs+1 {
+			cancel()
+		}
+		if job.ID == poison.ID {
+			panic("bad payload")
+		}
+		return nil
+	}
+
+	var errs []error
+	err := q.Work(ctx, handler, WorkerConfig{
+		Concurrency:  4,
+		PollInterval: time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Work failed: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("unexpected worker errors: %v", errs)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("job %d handled %d times", id, n)
+		}
+	}
+
+	finished, err := q.repo.Count(context.Background(), repository.NewSpec(&Job{}).Eq("status", StatusDone))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if finished != jobs {
+		t.Errorf("expected %d done jobs, got %d", jobs, finished)
+	}
+	dead, _ := q.DeadLetters(context.Background())
+	if len(dead) != 1 || dead[0].ID != poison.ID {
+		t.Errorf("expected panicking job dead-lettered, got %+v", dead)
+	}
+}
+
+func TestQueue_WorkStopsOnCancel(t *testing.T) {
+	q := setupQueue(t, Config{})
+	mustEnqueue(t, q, "in flight")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	var finished atomic.Bool
+	handler := func(ctx context.Context, job *Job) error {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		finished.Store(true)
+		return nil
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- q.Work(ctx, handler, WorkerConfig{PollInterval: time.Millisecond})
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Work failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Work did not return after cancellation")
+	}
+	if !finished.Load() {
+		t.Error("expected the in-flight job to finish before Work returned")
+	}
+	n, _ := q.repo.Count(context.Background(), repository.NewSpec(&Job{}).Eq("status", StatusDone))
+	if n != 1 {
+		t.Errorf("expected the in-flight job completed, got %d done", n)
+	}
+}
@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Handler processes a job. Returning an error makes the queue retry or dead-letter the job.
+type Handler func(ctx context.Context, job *Job) error
+
+// WorkerConfig configures Queue.Work. Zero values select the defaults.
+type WorkerConfig struct {
+	// Number of jobs processed concurrently (default 1)
+	Concurrency int
+	// Wait between polls when the queue is empty (default 1s)
+	PollInterval time.Duration
+	// Called with errors that do not stop the workers: failed dequeues and acknowledgements
+	OnError func(error)
+}
+
+// Work runs a pool of workers processing the queue with handler until ctx is cancelled.
+//
+// Shutdown is graceful: once ctx is cancelled no new job is dequeued and Work returns after the
+// jobs in flight finished. Handlers run with a context detached from ctx and bounded by the
+// visibility timeout, after which the job may be handed to another worker.
+func (q *Queue) Work(ctx context.Context, handler Handler, cfg WorkerConfig) error {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(error) {}
+	}
+
+	var wg sync.WaitGroup
+	for range cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.workLoop(ctx, handler, cfg)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// workLoop dequeues and processes jobs until ctx is cancelled.
+func (q *Queue) workLoop(ctx context.Context, handler Handler, cfg WorkerConfig) {
+	for ctx.Err() == nil {
+		job, err := q.Dequeue(ctx)
+		if err != nil && ctx.Err() == nil {
+			cfg.OnError(err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(cfg.PollInterval):
+			}
+			continue
+		}
+		if err := q.process(context.WithoutCancel(ctx), handler, job); err != nil {
+			cfg.OnError(err)
+		}
+	}
+}
+
+// process runs handler on job and acknowledges the outcome.
+func (q *Queue) process(ctx context.Context, handler Handler, job *Job) error {
+	jobCtx, cancel := context.WithTimeout(ctx, q.cfg.VisibilityTimeout)
+	err := run(jobCtx, handler, job)
+	cancel()
+	if err != nil {
+		return q.Fail(ctx, job, err)
+	}
+	return q.Complete(ctx, job)
+}
+
+// run calls handler, turning a panic into an error.
+func run(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("gormr: job %d panicked: %v", job.ID, p)
+		}
+	}()
+	return handler(ctx, job)
+}
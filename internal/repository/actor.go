@@ -0,0 +1,17 @@
+package repository
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor (user, service, ...) performing the operations
+// run with it. Plugins such as the audit trail read it back with ActorFromContext.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by ctx, if any.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok && actor != ""
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Aggregation describes an aggregate query over the records matching a Spec: the groups, the
+// aggregates computed per group and the conditions on them. Build it with NewAggregation and run
+// it with Repository.Aggregate.
+type Aggregation struct {
+	spec       *Spec
+	groups     []string
+	aggregates []aggregate
+	having     []condition
+	distinct   bool
+}
+
+// aggregate is an aggregate function selected as alias.
+type aggregate struct {
+	fn       string
+	column   string // empty for COUNT(*)
+	alias    string
+	distinct bool
+}
+
+// aliasPattern matches the aliases aggregates may be selected as.
+var aliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewAggregation creates an Aggregation over the records matching spec, which must have a model.
+// Its ordering (e.g. "total desc") and limit apply to the aggregated rows.
+func NewAggregation(spec *Spec) *Aggregation {
+	return &Aggregation{spec: spec}
+}
+
+// GroupBy groups the records by columns: field or column names of the model, or "table.column"
+// for joined tables (aliased with the association name, e.g. "Customer.name", or
+// "Order__Customer.name" for nested joins). The columns are selected under their column names.
+func (a *Aggregation) GroupBy(columns ...string) *Aggregation {
+	a.groups = append(a.groups, columns...)
+	return a
+}
+
+// Count selects the number of records as alias.
+func (a *Aggregation) Count(alias string) *Aggregation {
+	return a.add("COUNT", "", alias, false)
+}
+
+// CountDistinct selects the number of distinct non-null values of column as alias.
+func (a *Aggregation) CountDistinct(column, alias string) *Aggregation {
+	return a.add("COUNT", column, alias, true)
+}
+
+// Sum selects the sum of column as alias.
+func (a *Aggregation) Sum(column, alias string) *Aggregation {
+	return a.add("SUM", column, alias, false)
+}
+
+// Avg selects the average of column as alias.
+func (a *Aggregation) Avg(column, alias string) *Aggregation {
+	return a.add("AVG", column, alias, false)
+}
+
+// Min selects the smallest value of column as alias.
+func (a *Aggregation) Min(column, alias string) *Aggregation {
+	return a.add("MIN", column, alias, false)
+}
+
+// Max selects the largest value of column as alias.
+func (a *Aggregation) Max(column, alias string) *Aggregation {
+	return a.add("MAX", column, alias, false)
+}
+
+// Having adds a raw condition on the groups (e.g. "COUNT(*) > ?", 1). Conditions are joined with
+// AND. Postgres does not accept aliases in HAVING: repeat the aggregate expression.
+func (a *Aggregation) Having(query string, args ...any) *Aggregation {
+	a.having = append(a.having, condition{query: query, args: args})
+	return a
+}
+
+// Distinct selects distinct rows only, e.g. to list the distinct values of the GroupBy columns.
+func (a *Aggregation) Distinct() *Aggregation {
+	a.distinct = true
+	return a
+}
+
+func (a *Aggregation) add(fn, column, alias string, distinct bool) *Aggregation {
+	a.aggregates = append(a.aggregates, aggregate{fn: fn, column: column, alias: alias, distinct: distinct})
+	return a
+}
+
+// Aggregate runs agg and scans its rows into out: a pointer to a struct (without GroupBy), to a
+// slice of structs whose fields match the group columns and aggregate aliases, or to a
+// []map[string]any. Columns are checked against the model schema: unknown ones fail with
+// ErrUnknownColumn. opts apply like for Find (e.g. WithTrashed or Joins).
+func (r *Repository) Aggregate(ctx context.Context, agg *Aggregation, out any, opts ...QueryOption) error {
+	return r.do(ctx, OpAggregate, agg.model(), []any{&agg, &out, &opts}, func(ctx context.Context) error {
+		model := agg.model()
+		if model == nil {
+			return errors.New("gormr: Aggregate needs a Spec with a model")
+		}
+		s, err := r.schemaOf(model)
+		if err != nil {
+			return err
+		}
+		q, err := r.countQuery(ctx, model, opts)
+		if err != nil {
+			return err
+		}
+		q, err = agg.apply(q, s)
+		if err != nil {
+			return err
+		}
+		return q.Find(out).Error
+	})
+}
+
+// model returns the model the Aggregation targets.
+func (a *Aggregation) model() any {
+	if a == nil {
+		return nil
+	}
+	return a.spec.Model()
+}
+
+// apply adds the selected columns, grouping and conditions of a to q, a query on s.
+func (a *Aggregation) apply(q *gorm.DB, s *schema.Schema) (*gorm.DB, error) {
+	if len(a.groups) == 0 && len(a.aggregates) == 0 {
+		return nil, errors.New("gormr: aggregation selects nothing, add GroupBy columns or aggregates")
+	}
+	var selects, groups []string
+	for _, g := range a.groups {
+		col, err := aggregateColumn(q, s, g)
+		if err != nil {
+			return nil, err
+		}
+		selects = append(selects, col)
+		groups = append(groups, col)
+	}
+	for _, ag := range a.aggregates {
+		if !aliasPattern.MatchString(ag.alias) {
+			return nil, fmt.Errorf("gormr: invalid aggregate alias %q", ag.alias)
+		}
+		arg := "*"
+		if ag.column != "" {
+			col, err := aggregateColumn(q, s, ag.column)
+			if err != nil {
+				return nil, err
+			}
+			arg = col
+		}
+		if ag.distinct {
+			arg = "DISTINCT " + arg
+		}
+		selects = append(selects, fmt.Sprintf("%s(%s) AS %s", ag.fn, arg, q.Statement.Quote(ag.alias)))
+	}
+
+	q = q.Select(strings.Join(selects, ", "))
+	if a.distinct {
+		q = q.Distinct()
+	}
+	q = a.spec.apply(q)
+	if len(groups) > 0 {
+		q = q.Group(strings.Join(groups, ", "))
+	}
+	for _, h := range a.having {
+		q = q.Having(h.query, h.args...)
+	}
+	return q, nil
+}
+
+// aggregateColumn returns the quoted column of s named name (a field or column name), or name
+// quoted as is when it is qualified with a table.
+func aggregateColumn(q *gorm.DB, s *schema.Schema, name string) (string, error) {
+	if strings.Contains(name, ".") {
+		return q.Statement.Quote(name), nil
+	}
+	f := s.LookUpField(name)
+	if f == nil || f.DBName == "" {
+		return "", fmt.Errorf("%w: %s has no column %q", ErrUnknownColumn, s.Name, name)
+	}
+	return q.Statement.Quote(f.DBName), nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func setupCarStats(t *testing.T) *Repository {
+	t.Helper()
+	repo := New(setupTestDB(t))
+	cars := []Car{
+		{Brand: "Toyota", Color: "Red", Year: 2020},
+		{Brand: "Toyota", Color: "Blue", Year: 2016},
+		{Brand: "Toyota", Color: "Red", Year: 2018},
+		{Brand: "Ford", Color: "Blue", Year: 2018},
+		{Brand: "Peugeot", Color: "White", Year: 2019},
+	}
+	if err := repo.CreateBatch(context.Background(), &cars, 10); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	return repo
+}
+
+func TestRepository_Aggregate(t *testing.T) {
+	repo := setupCarStats(t)
+	ctx := context.Background()
+
+	type brandStats struct {
+		Brand  string
+		N      int64
+		Colors int64
+		Oldest int
+		Newest int
+		AvgAge float64 `gorm:"column:avg_year"`
+	}
+	var stats []brandStats
+	agg := NewAggregation(NewSpec(&Car{}).Where("year >= ?", 2017).OrderBy("n desc")).
+		GroupBy("Brand").Count("n").CountDistinct("color", "colors").
+		Min("year", "oldest").Max("Year", "newest").Avg("year", "avg_year")
+	if err := repo.Aggregate(ctx, agg, &stats); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 brands, got %+v", stats)
+	}
+	want := brandStats{Brand: "Toyota", N: 2, Colors: 1, Oldest: 2018, Newest: 2020, AvgAge: 2019}
+	if stats[0] != want {
+		t.Errorf("expected %+v, got %+v", want, stats[0])
+	}
+
+	// Having and maps
+	var rows []map[string]any
+	agg = NewAggregation(NewSpec(&Car{}).OrderBy("color")).GroupBy("color").Sum("year", "total").Having("COUNT(*) > ?", 1)
+	if err := repo.Aggregate(ctx, agg, &rows); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0]["color"] != "Blue" || rows[0]["total"] != int64(4034) {
+		t.Errorf("expected Blue and Red, got %v", rows)
+	}
+
+	// Without groups, into a struct
+	var total struct{ N, Brands int64 }
+	if err := repo.Aggregate(ctx, NewAggregation(NewSpec(&Car{})).Count("n").CountDistinct("brand", "brands"), &total); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if total.N != 5 || total.Brands != 3 {
+		t.Errorf("expected 5 cars of 3 brands, got %+v", total)
+	}
+
+	var colors []string
+	if err := repo.Aggregate(ctx, NewAggregation(NewSpec(&Car{}).OrderBy("color")).GroupBy("color").Distinct(), &colors); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(colors) != 3 || colors[0] != "Blue" {
+		t.Errorf("expected 3 distinct colors, got %v", colors)
+	}
+}
+
+func TestRepository_AggregateJoins(t *testing.T) {
+	repo := setupLibrary(t)
+	ctx := context.Background()
+	var captured string
+	err := repo.db.Callback().Query().After("gorm:query").Register("test:capture", func(db *gorm.DB) {
+		captured = db.Statement.SQL.String()
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	var rows []struct {
+		Name    string
+		Reviews int64
+	}
+	agg := NewAggregation(NewSpec(&Review{}).OrderBy("reviews desc")).GroupBy("Book__Author.name").Count("reviews")
+	if err := repo.Aggregate(ctx, agg, &rows, InnerJoins("Book.Author")); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Name != "Le Guin" || rows[0].Reviews != 2 {
+		t.Errorf("expected reviews per author, got %+v", rows)
+	}
+	// Joined columns are not selected: they are neither grouped nor aggregated
+	if cols, _, _ := strings.Cut(captured, " FROM "); cols != "SELECT `Book__Author`.`name`, COUNT(*) AS `reviews`" {
+		t.Errorf("expected only the group and aggregate to be selected, got %s", captured)
+	}
+
+	var maps []map[string]any
+	agg = NewAggregation(NewSpec(&Review{})).GroupBy("Book.title").Sum("stars", "stars")
+	if err := repo.Aggregate(ctx, agg, &maps, Joins("Book")); err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	for _, row := range maps {
+		if len(row) != 2 {
+			t.Errorf("expected only title and stars, got %v", row)
+		}
+	}
+}
+
+func TestRepository_AggregateErrors(t *testing.T) {
+	repo := setupCarStats(t)
+	ctx := context.Background()
+	var rows []map[string]any
+
+	cases := map[string]*Aggregation{
+		"unknown group":     NewAggregation(NewSpec(&Car{})).GroupBy("price"),
+		"unknown aggregate": NewAggregation(NewSpec(&Car{})).Sum("price; DROP TABLE cars", "total"),
+	}
+	for name, agg := range cases {
+		if err := repo.Aggregate(ctx, agg, &rows); !errors.Is(err, ErrUnknownColumn) {
+			t.Errorf("%s: expected ErrUnknownColumn, got %v", name, err)
+		}
+	}
+	for name, agg := range map[string]*Aggregation{
+		"invalid alias": NewAggregation(NewSpec(&Car{})).Count("n; --"),
+		"empty":         NewAggregation(NewSpec(&Car{})),
+		"no model":      NewAggregation(nil).Count("n"),
+	} {
+		if err := repo.Aggregate(ctx, agg, &rows); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
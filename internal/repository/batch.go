@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+)
+
+// UpsertOptions configures how Upsert resolves conflicts.
+// Conflicts are translated per driver: ON CONFLICT for Postgres and SQLite, ON DUPLICATE KEY UPDATE
+// for MySQL and MERGE for SQL Server.
+type UpsertOptions struct {
+	// Columns identifying a conflict (default: primary key). MySQL ignores them and resolves
+	// conflicts against every unique index; SQL Server only supports the primary key.
+	ConflictColumns []string
+	// Columns overwritten on conflict (default: every column except primary key and creation time)
+	UpdateColumns []string
+	// Leave conflicting rows untouched instead of updating them
+	DoNothing bool
+	// Number of rows per INSERT statement (default: all rows in one statement)
+	BatchSize int
+}
+
+// UpsertResult reports the outcome of Upsert.
+type UpsertResult struct {
+	// Rows reported as affected by the database
+	RowsAffected int64
+	// Rows inserted
+	Inserted int64
+	// Rows updated on conflict
+	Updated int64
+	// Conflicting rows left untouched (DoNothing)
+	Skipped int64
+	// Whether Inserted, Updated and Skipped could be derived for the dialect. Only MySQL reports
+	// them (1 affected row per insert, 2 per update, 0 per untouched row); rows whose values did
+	// not change on update are reported as inserted. Dialects that read the inserted keys back
+	// (RETURNING/OUTPUT) only report RowsAffected.
+	Exact bool
+}
+
+// CreateBatch inserts entities (a slice or a pointer to a slice) using batchSize rows per statement.
+func (r *Repository) CreateBatch(ctx context.Context, entities any, batchSize int) error {
+	return r.do(ctx, OpCreateBatch, entities, []any{&entities, &batchSize}, func(ctx context.Context) error {
+		if batchSize <= 0 {
+			return ErrInvalidBatchSize
+		}
+		return r.conn(ctx).CreateInBatches(entities, batchSize).Error
+	})
+}
+
+// Upsert inserts entities (an entity, a slice or a pointer to a slice), resolving conflicts as opts describes.
+func (r *Repository) Upsert(ctx context.Context, entities any, opts UpsertOptions) (UpsertResult, error) {
+	return call(ctx, r, OpUpsert, entities, []any{&entities, &opts}, func(ctx context.Context) (UpsertResult, error) {
+		return r.upsert(ctx, entities, opts)
+	})
+}
+
+// upsert is Upsert without the middleware chain.
+func (r *Repository) upsert(ctx context.Context, entities any, opts UpsertOptions) (UpsertResult, error) {
+	if opts.BatchSize < 0 {
+		return UpsertResult{}, ErrInvalidBatchSize
+	}
+	dialect := r.dialect()
+	if dialect == dialectSQLServer && len(opts.ConflictColumns) > 0 {
+		if err := r.checkPrimaryKeyConflict(entities, opts.ConflictColumns); err != nil {
+			return UpsertResult{}, err
+		}
+	}
+
+	conflict := clause.OnConflict{DoNothing: opts.DoNothing}
+	for _, c := range opts.ConflictColumns {
+		conflict.Columns = append(conflict.Columns, clause.Column{Name: c})
+	}
+	switch {
+	case opts.DoNothing:
+	case len(opts.UpdateColumns) > 0:
+		conflict.DoUpdates = clause.AssignmentColumns(opts.UpdateColumns)
+	default:
+		conflict.UpdateAll = true
+	}
+
+	n := int64(countEntities(entities))
+	q := r.conn(ctx).Clauses(conflict)
+	if opts.BatchSize > 0 {
+		q = q.CreateInBatches(entities, opts.BatchSize)
+	} else {
+		q = q.Create(entities)
+	}
+	if q.Error != nil {
+		return UpsertResult{}, q.Error
+	}
+	return upsertResult(dialect, n, q.RowsAffected, opts.DoNothing), nil
+}
+
+// checkPrimaryKeyConflict verifies the conflict columns are the primary key, the only conflict
+// target GORM's SQL Server MERGE supports.
+func (r *Repository) checkPrimaryKeyConflict(entities any, columns []string) error {
+	s, err := r.schemaOf(entities)
+	if err != nil {
+		return err
+	}
+	pk := map[string]bool{}
+	for _, f := range s.PrimaryFields {
+		pk[f.DBName] = true
+	}
+	if len(columns) != len(pk) {
+		return fmt.Errorf("gormr: SQLServer upsert only supports primary key conflicts, got %v", columns)
+	}
+	for _, c := range columns {
+		if !pk[c] {
+			return fmt.Errorf("gormr: SQLServer upsert only supports primary key conflicts, got %v", columns)
+		}
+	}
+	return nil
+}
+
+// upsertResult derives the inserted/updated/skipped split of n rows from the affected row count.
+// Only MySQL reports affected rows in a way the split can be derived from.
+func upsertResult(dialect string, n, affected int64, doNothing bool) UpsertResult {
+	res := UpsertResult{RowsAffected: affected}
+	if dialect != dialectMySQL {
+		return res
+	}
+	res.Exact = true
+	if doNothing {
+		res.Inserted = affected
+		res.Skipped = n - affected
+		return res
+	}
+	res.Updated = max(affected-n, 0)
+	res.Inserted = n - res.Updated
+	return res
+}
+
+// countEntities returns the number of rows in entities: the slice length, or 1 for a single entity.
+func countEntities(entities any) int {
+	v := reflect.Indirect(reflect.ValueOf(entities))
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return v.Len()
+	}
+	return 1
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCarRepository_CreateBatch(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	cars := append([]Car(nil), carPageTestData...)
+	if err := repo.CreateBatch(ctx, &cars, 2); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	for _, c := range cars {
+		if c.ID == 0 {
+			t.Fatalf("expected IDs to be set after CreateBatch, got %+v", c)
+		}
+	}
+
+	total, err := repo.Count(ctx, NewSpec(&Car{}))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != int64(len(carPageTestData)) {
+		t.Errorf("expected %d cars, got %d", len(carPageTestData), total)
+	}
+
+	if err := repo.CreateBatch(ctx, &cars, 0); !errors.Is(err, ErrInvalidBatchSize) {
+		t.Errorf("expected ErrInvalidBatchSize, got %v", err)
+	}
+}
+
+func TestCarRepository_Upsert(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	existing := Car{Brand: "Toyota", Color: "Red", Year: 2020, Model: "Corolla"}
+	if err := repo.Create(ctx, &existing); err != nil {
+		t.Fatalf("failed to create car: %v", err)
+	}
+
+	cars := []Car{
+		{ID: existing.ID, Brand: "Toyota", Color: "Black", Year: 2021, Model: "Corolla"},
+		{Brand: "Ford", Color: "Blue", Year: 2018, Model: "Focus"},
+	}
+	res, err := repo.Upsert(ctx, &cars, UpsertOptions{ConflictColumns: []string{"id"}, UpdateColumns: []string{"color"}})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if res.RowsAffected != 2 {
+		t.Errorf("expected 2 affected rows, got %d", res.RowsAffected)
+	}
+
+	var got Car
+	if err := repo.GetByID(ctx, &Car{}, existing.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Color != "Black" || got.Year != 2020 {
+		t.Errorf("expected only color to be updated, got %+v", got)
+	}
+}
+
+func TestCarRepository_UpsertDoNothing(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	existing := Car{Brand: "Toyota", Color: "Red", Year: 2020, Model: "Corolla"}
+	if err := repo.Create(ctx, &existing); err != nil {
+		t.Fatalf("failed to create car: %v", err)
+	}
+
+	cars := []Car{
+		{ID: existing.ID, Brand: "Toyota", Color: "Black", Year: 2021, Model: "Corolla"},
+		{Brand: "Ford", Color: "Blue", Year: 2018, Model: "Focus"},
+		{Brand: "Fiat", Color: "Yellow", Year: 2015, Model: "Uno"},
+	}
+	if _, err := repo.Upsert(ctx, cars, UpsertOptions{DoNothing: true, BatchSize: 2}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	total, err := repo.Count(ctx, NewSpec(&Car{}))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 cars after upsert, got %d", total)
+	}
+
+	var got Car
+	if err := repo.GetByID(ctx, &Car{}, existing.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Color != "Red" {
+		t.Errorf("expected conflicting car to be untouched, got %+v", got)
+	}
+}
+
+func TestUpsertResult(t *testing.T) {
+	for name, tt := range upsertResultTestCases {
+		t.Run(name, func(t *testing.T) {
+			got := upsertResult(tt.dialect, tt.n, tt.affected, tt.doNothing)
+			if got != tt.want {
+				t.Errorf("upsertResult() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPrimaryKeyConflict(t *testing.T) {
+	repo := New(setupTestDB(t))
+
+	if err := repo.checkPrimaryKeyConflict(&Car{}, []string{"id"}); err != nil {
+		t.Errorf("expected primary key conflict to be accepted, got %v", err)
+	}
+	if err := repo.checkPrimaryKeyConflict(&Car{}, []string{"brand"}); err == nil {
+		t.Error("expected non primary key conflict to be rejected")
+	}
+}
@@ -0,0 +1,13 @@
+package repository
+
+// Dialect names reported by GORM dialectors. They match the db.DBDriver values.
+const (
+	dialectMySQL     = "mysql"
+	dialectSQLite    = "sqlite"
+	dialectSQLServer = "sqlserver"
+)
+
+// dialect returns the name of the dialector behind the repository.
+func (r *Repository) dialect() string {
+	return r.db.Dialector.Name()
+}
@@ -0,0 +1,48 @@
+package repository
+
+import "errors"
+
+// ErrInvalidPage is returned by Paginate when the page or the page size is lower than 1.
+var ErrInvalidPage = errors.New("gormr: page and page size must be greater than zero")
+
+// ErrInvalidBatchSize is returned by batch operations when the batch size is lower than 1.
+var ErrInvalidBatchSize = errors.New("gormr: batch size must be greater than zero")
+
+// ErrNoChanges is returned by partial updates called without fields or changes.
+var ErrNoChanges = errors.New("gormr: no fields to update")
+
+// ErrStaleObject is returned when a versioned entity was changed or deleted by someone else
+// since it was loaded, so the update or delete matched no row.
+var ErrStaleObject = errors.New("gormr: stale object, the record was modified or deleted concurrently")
+
+// ErrSoftDeleteUnsupported is returned by soft delete operations on models without a gorm.DeletedAt field.
+var ErrSoftDeleteUnsupported = errors.New("gormr: model does not support soft delete (missing gorm.DeletedAt field)")
+
+// ErrNotInTransaction is returned by operations that require a transaction when called outside one.
+var ErrNotInTransaction = errors.New("gormr: operation requires a transaction")
+
+// ErrTransactionExists is returned by PropagationNever when a transaction is active.
+var ErrTransactionExists = errors.New("gormr: operation must not run inside a transaction")
+
+// ErrLockOutsideTransaction is returned by reads using WithLock outside a transaction, where the
+// lock would be released as soon as the statement ends.
+var ErrLockOutsideTransaction = errors.New("gormr: row locks can only be taken inside a transaction")
+
+// ErrUnknownAssociation is returned by finds given a Preload or Joins path naming no association
+// of the model.
+var ErrUnknownAssociation = errors.New("gormr: unknown association")
+
+// ErrUnsupportedJoin is returned by finds given a Joins path through a has-many or many-to-many
+// association, which must be preloaded instead.
+var ErrUnsupportedJoin = errors.New("gormr: association cannot be joined")
+
+// ErrUnsupportedPreload is returned by Iterate given a Preload option: rows are scanned one by
+// one, so use Joins or FindInBatches instead.
+var ErrUnsupportedPreload = errors.New("gormr: Iterate does not support Preload")
+
+// ErrUnmappedField is returned by finds given StrictDTO scanning into a DTO with a field matching
+// no column of the model.
+var ErrUnmappedField = errors.New("gormr: DTO field matches no column")
+
+// ErrUnknownColumn is returned by aggregations naming a column the model does not have.
+var ErrUnknownColumn = errors.New("gormr: unknown column")
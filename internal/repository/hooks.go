@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// commitHooks holds the AfterCommit hooks of the transactions started by the repositories,
+// keyed by the connection of the transaction.
+var commitHooks sync.Map // gorm.ConnPool -> *txHooks
+
+// txHooks are the functions to run once a transaction commits.
+type txHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// watchCommit registers tx so AfterCommit hooks can be attached to it, until unwatch.
+func watchCommit(tx *gorm.DB) *txHooks {
+	h := &txHooks{}
+	commitHooks.Store(tx.Statement.ConnPool, h)
+	return h
+}
+
+// unwatch stops collecting the hooks of tx.
+func unwatch(tx *gorm.DB) {
+	commitHooks.Delete(tx.Statement.ConnPool)
+}
+
+func (h *txHooks) add(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fns = append(h.fns, fn)
+}
+
+// run calls the hooks in registration order.
+func (h *txHooks) run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// InTransaction reports whether operations run with ctx join a transaction: the one r is bound
+// to (txRepo) or the one carried by ctx.
+func (r *Repository) InTransaction(ctx context.Context) bool {
+	return r.activeTx(ctx) != nil
+}
+
+// AfterCommit runs fn once the active transaction commits, or right away when there is none.
+// Hooks are dropped when the transaction rolls back; hooks registered in a nested transaction
+// rolled back to its savepoint still run if the outer transaction commits. Transactions started
+// with the deprecated ManualTx don't support hooks: fn runs right away.
+func (r *Repository) AfterCommit(ctx context.Context, fn func()) {
+	if tx := r.activeTx(ctx); tx != nil {
+		if h, ok := commitHooks.Load(tx.Statement.ConnPool); ok {
+			h.(*txHooks).add(fn)
+			return
+		}
+	}
+	fn()
+}
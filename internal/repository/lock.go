@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LockMode selects the row lock taken by a read (see WithLock). Combine one strength with at most
+// one wait policy, e.g. ForUpdate|SkipLocked.
+type LockMode int
+
+const (
+	// ForUpdate locks the rows read for writing (SELECT ... FOR UPDATE).
+	ForUpdate LockMode = 1 << iota
+	// ForShare locks the rows read against writes by others (SELECT ... FOR SHARE).
+	ForShare
+	// NoWait fails immediately instead of waiting for rows locked by others.
+	NoWait
+	// SkipLocked leaves out the rows locked by others.
+	SkipLocked
+)
+
+// WithLock locks the rows read with mode until the end of the transaction. It is translated per
+// driver: FOR UPDATE/FOR SHARE [NOWAIT|SKIP LOCKED] for Postgres and MySQL, and UPDLOCK/HOLDLOCK,
+// ROWLOCK [NOWAIT|READPAST] table hints for SQL Server. SQLite has no row locks, its transactions
+// lock the whole database, so the option is accepted without effect.
+// Locking reads outside a transaction fail with ErrLockOutsideTransaction.
+func WithLock(mode LockMode) QueryOption {
+	return func(o *queryOptions) {
+		o.lock = mode
+	}
+}
+
+// validate checks mode combines exactly one strength with at most one wait policy.
+func (m LockMode) validate() error {
+	strength := m & (ForUpdate | ForShare)
+	wait := m & (NoWait | SkipLocked)
+	if strength != ForUpdate && strength != ForShare || wait == NoWait|SkipLocked {
+		return fmt.Errorf("gormr: invalid lock mode %d: combine ForUpdate or ForShare with at most one of NoWait, SkipLocked", m)
+	}
+	return nil
+}
+
+// applyLock adds the dialect-specific locking of mode to q, a query on table.
+func applyLock(q *gorm.DB, dialect string, mode LockMode, table string) *gorm.DB {
+	switch dialect {
+	case dialectSQLite:
+		return q
+	case dialectSQLServer:
+		hints := []string{"UPDLOCK", "ROWLOCK"}
+		if mode&ForShare != 0 {
+			hints = []string{"HOLDLOCK", "ROWLOCK"}
+		}
+		switch {
+		case mode&NoWait != 0:
+			hints = append(hints, "NOWAIT")
+		case mode&SkipLocked != 0:
+			hints = append(hints, "READPAST")
+		}
+		return q.Table(fmt.Sprintf("%s WITH (%s)", q.Statement.Quote(table), strings.Join(hints, ", ")))
+	default:
+		locking := clause.Locking{Strength: clause.LockingStrengthUpdate}
+		if mode&ForShare != 0 {
+			locking.Strength = clause.LockingStrengthShare
+		}
+		switch {
+		case mode&NoWait != 0:
+			locking.Options = clause.LockingOptionsNoWait
+		case mode&SkipLocked != 0:
+			locking.Options = clause.LockingOptionsSkipLocked
+		}
+		return q.Clauses(locking)
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+func TestCarRepository_LockOutsideTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	var got Car
+	if err := repo.GetByID(ctx, &Car{}, 1, &got, WithLock(ForUpdate)); !errors.Is(err, ErrLockOutsideTransaction) {
+		t.Errorf("expected ErrLockOutsideTransaction, got %v", err)
+	}
+}
+
+func TestCarRepository_LockInTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	seedCars(t, repo, carPageTestData)
+
+	err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		var cars []Car
+		if err := repo.Find(ctx, NewSpec(&Car{}).Eq("year", 2019), &cars, WithLock(ForUpdate|SkipLocked)); err != nil {
+			return err
+		}
+		if len(cars) != 2 {
+			t.Errorf("expected 2 locked cars, got %d", len(cars))
+		}
+		page, err := Paginate[Car](ctx, repo, nil, PageRequest{Page: 1, PageSize: 2}, WithLock(ForShare))
+		if err != nil {
+			return err
+		}
+		if page.Total != int64(len(carPageTestData)) {
+			t.Errorf("expected total %d, got %d", len(carPageTestData), page.Total)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("locking reads failed: %v", err)
+	}
+}
+
+func TestLockMode_Validate(t *testing.T) {
+	tests := map[string]struct {
+		mode    LockMode
+		wantErr bool
+	}{
+		"for_update":             {mode: ForUpdate},
+		"for_share_nowait":       {mode: ForShare | NoWait},
+		"for_update_skip_locked": {mode: ForUpdate | SkipLocked},
+		"no_strength":            {mode: SkipLocked, wantErr: true},
+		"both_strengths":         {mode: ForUpdate | ForShare, wantErr: true},
+		"both_wait_policies":     {mode: ForUpdate | NoWait | SkipLocked, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := tt.mode.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyLock(t *testing.T) {
+	cfg := &gorm.Config{DryRun: true, DisableAutomaticPing: true}
+	pg, err := gorm.Open(postgres.Open("host=localhost"), cfg)
+	if err != nil {
+		t.Fatalf("failed to open postgres dry run: %v", err)
+	}
+	mssql, err := gorm.Open(sqlserver.Open("sqlserver://localhost"), cfg)
+	if err != nil {
+		t.Fatalf("failed to open sqlserver dry run: %v", err)
+	}
+
+	tests := map[string]struct {
+		db   *gorm.DB
+		mode LockMode
+		want string
+	}{
+		"postgres_for_update_skip_locked": {db: pg, mode: ForUpdate | SkipLocked, want: "FOR UPDATE SKIP LOCKED"},
+		"postgres_for_share_nowait":       {db: pg, mode: ForShare | NoWait, want: "FOR SHARE NOWAIT"},
+		"sqlserver_for_update":            {db: mssql, mode: ForUpdate, want: `FROM "cars" WITH (UPDLOCK, ROWLOCK)`},
+		"sqlserver_skip_locked":           {db: mssql, mode: ForShare | SkipLocked, want: `WITH (HOLDLOCK, ROWLOCK, READPAST)`},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			q := applyLock(tt.db.Model(&Car{}), tt.db.Dialector.Name(), tt.mode, "cars")
+			sql := q.Where("year = ?", 2019).Find(&[]Car{}).Statement.SQL.String()
+			if !strings.Contains(sql, tt.want) {
+				t.Errorf("SQL = %q, want it to contain %q", sql, tt.want)
+			}
+		})
+	}
+}
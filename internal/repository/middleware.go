@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Operation names passed to middlewares, one per Repository method (or generic helper) they wrap.
+const (
+	OpCreate        = "Create"
+	OpCreateBatch   = "CreateBatch"
+	OpUpsert        = "Upsert"
+	OpUpdate        = "Update"
+	OpUpdateFields  = "UpdateFields"
+	OpUpdateMap     = "UpdateMap"
+	OpUpdateWhere   = "UpdateWhere"
+	OpDelete        = "Delete"
+	OpDeleteByID    = "DeleteByID"
+	OpDeleteWhere   = "DeleteWhere"
+	OpSoftDelete    = "SoftDelete"
+	OpRestore       = "Restore"
+	OpForceDelete   = "ForceDelete"
+	OpPurge         = "Purge"
+	OpGetByID       = "GetByID"
+	OpGetAll        = "GetAll"
+	OpGetPaginated  = "GetPaginated"
+	OpGetByField    = "GetByField"
+	OpFind          = "Find"
+	OpCount         = "Count"
+	OpAggregate     = "Aggregate"
+	OpPaginate      = "Paginate"
+	OpIterate       = "Iterate"
+	OpFindInBatches = "FindInBatches"
+)
+
+// Operation is a Repository call passing through the middleware chain.
+type Operation struct {
+	// Name of the method, one of the Op* constants
+	Name string
+	// Entity or model the operation targets (the Spec model, or a *T, for Spec based operations)
+	Model any
+	// Arguments following ctx, in the order of the method signature (variadic options as a slice).
+	// Middlewares may replace them with values of the same type before calling the next handler.
+	Args []any
+	// Repository running the operation (a txRepo inside Transaction). Middlewares querying the
+	// database through it with the ctx they receive join the operation's transaction.
+	Repo *Repository
+}
+
+// Handler executes an Operation. Besides the error it returns the result of the method, if any
+// (e.g. the int64 of Count or the *Page[T] of Paginate), and nil otherwise.
+type Handler func(ctx context.Context, op *Operation) (any, error)
+
+// Middleware wraps the Handler executing operations. It may act before and after calling next,
+// change ctx or op.Args, observe or replace the result and error, or short-circuit the operation
+// by returning without calling next.
+type Middleware func(next Handler) Handler
+
+// Use appends middlewares to the chain wrapping every data operation of r. The first registered
+// middleware is the outermost one. txRepos and Tx handles created afterwards inherit the chain.
+// Use is meant for setup time and must not run concurrently with operations.
+func (r *Repository) Use(middlewares ...Middleware) {
+	r.middlewares = append(slices.Clip(r.middlewares), middlewares...)
+}
+
+// do runs fn through the middleware chain as the operation name. params point to the method
+// arguments; they are exposed as op.Args and updated from it before fn runs.
+func (r *Repository) do(ctx context.Context, name string, model any, params []any, fn func(ctx context.Context) error) error {
+	_, err := call(ctx, r, name, model, params, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// call is do for methods returning a result besides the error.
+func call[T any](ctx context.Context, r *Repository, name string, model any, params []any, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if len(r.middlewares) == 0 {
+		return fn(ctx)
+	}
+	op := &Operation{Name: name, Model: model, Args: make([]any, len(params)), Repo: r}
+	for i, p := range params {
+		op.Args[i] = reflect.ValueOf(p).Elem().Interface()
+	}
+
+	h := Handler(func(ctx context.Context, op *Operation) (any, error) {
+		if err := op.scan(params); err != nil {
+			return nil, err
+		}
+		return fn(ctx)
+	})
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+
+	res, err := h(ctx, op)
+	if res == nil {
+		return zero, err
+	}
+	out, ok := res.(T)
+	if !ok {
+		return zero, fmt.Errorf("gormr: middleware returned %T from %s, want %T", res, name, zero)
+	}
+	return out, err
+}
+
+// scan copies op.Args into the variables params point to.
+func (op *Operation) scan(params []any) error {
+	if len(op.Args) != len(params) {
+		return fmt.Errorf("gormr: middleware changed %s to %d arguments, want %d", op.Name, len(op.Args), len(params))
+	}
+	for i, p := range params {
+		dst := reflect.ValueOf(p).Elem()
+		if op.Args[i] == nil {
+			switch dst.Kind() {
+			case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func:
+				dst.SetZero()
+				continue
+			}
+			return fmt.Errorf("gormr: middleware set argument %d of %s to nil, want %s", i, op.Name, dst.Type())
+		}
+		v := reflect.ValueOf(op.Args[i])
+		if !v.Type().AssignableTo(dst.Type()) {
+			return fmt.Errorf("gormr: middleware set argument %d of %s to %s, want %s", i, op.Name, v.Type(), dst.Type())
+		}
+		dst.Set(v)
+	}
+	return nil
+}
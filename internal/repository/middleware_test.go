@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// recordOps returns a middleware appending "<tag>:<op>" before and "<tag>:<op>:done" after each operation.
+func recordOps(tag string, log *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (any, error) {
+			*log = append(*log, tag+":"+op.Name)
+			res, err := next(ctx, op)
+			*log = append(*log, tag+":"+op.Name+":done")
+			return res, err
+		}
+	}
+}
+
+func TestRepository_UseOrder(t *testing.T) {
+	repo := New(setupTestDB(t))
+	ctx := context.Background()
+	var log []string
+	repo.Use(recordOps("outer", &log), recordOps("inner", &log))
+
+	car := Car{Brand: "Mazda", Color: "Gray", Year: 2019, Model: "3"}
+	if err := repo.Create(ctx, &car); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	want := []string{"outer:Create", "inner:Create", "inner:Create:done", "outer:Create:done"}
+	if !slices.Equal(log, want) {
+		t.Errorf("expected %v, got %v", want, log)
+	}
+}
+
+func TestRepository_UseOperation(t *testing.T) {
+	repo := New(setupTestDB(t))
+	ctx := context.Background()
+	var ops []Operation
+	repo.Use(func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (any, error) {
+			ops = append(ops, *op)
+			return next(ctx, op)
+		}
+	})
+
+	var cars []Car
+	spec := NewSpec(&Car{}).Eq("brand", "Mazda")
+	if err := repo.Find(ctx, spec, &cars, WithTrashed()); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Name != OpFind || ops[0].Model != spec.Model() || len(ops[0].Args) != 3 {
+		t.Fatalf("unexpected operation %+v", ops)
+	}
+	if ops[0].Args[0] != spec || len(ops[0].Args[2].([]QueryOption)) != 1 {
+		t.Errorf("expected spec, out and options as arguments, got %+v", ops[0].Args)
+	}
+}
+
+func TestRepository_UseModifiesArgs(t *testing.T) {
+	repo := New(setupTestDB(t))
+	ctx := context.Background()
+	seedCars(t, repo, carByFieldTestData)
+	repo.Use(func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (any, error) {
+			if op.Name == OpGetByField {
+				op.Args[2] = "Toyota"
+			}
+			return next(ctx, op)
+		}
+	})
+
+	var cars []Car
+	if err := repo.GetByField(ctx, &Car{}, "brand", "Peugeot", &cars); err != nil {
+		t.Fatalf("GetByField failed: %v", err)
+	}
+	if len(cars) != 1 || cars[0].Brand != "Toyota" {
+		t.Errorf("expected the middleware's value to be used, got %+v", cars)
+	}
+}
+
+func TestRepository_UseInvalidArg(t *testing.T) {
+	repo := New(setupTestDB(t))
+	repo.Use(func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (any, error) {
+			op.Args[1] = "ten"
+			return next(ctx, op)
+		}
+	})
+	if err := repo.CreateBatch(context.Background(), []Car{{Brand: "Fiat"}}, 10); err == nil {
+		t.Error("expected an error for an argument of the wrong type")
+	}
+}
+
+func TestRepository_UseShortCircuit(t *testing.T) {
+	repo := New(setupTestDB(t))
+	ctx := context.Background()
+	denied := errors.New("denied")
+	repo.Use(func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (any, error) {
+			switch op.Name {
+			case OpCount:
+				return int64(42), nil
+			case OpCreate:
+				return nil, denied
+			}
+			return next(ctx, op)
+		}
+	})
+
+	n, err := repo.Count(ctx, NewSpec(&Car{}))
+	if err != nil || n != 42 {
+		t.Errorf("expected the middleware's count, got %d (err %v)", n, err)
+	}
+	if err := repo.Create(ctx, &Car{Brand: "Fiat"}); !errors.Is(err, denied) {
+		t.Errorf("expected denied, got %v", err)
+	}
+	if page, _ := Paginate[Car](ctx, repo, nil, PageRequest{Page: 1, PageSize: 10}); page == nil || len(page.Items) != 0 {
+		t.Errorf("expected an empty page, got %+v", page)
+	}
+}
+
+func TestRepository_UseObservesErrors(t *testing.T) {
+	repo := New(setupTestDB(t))
+	var seen []error
+	repo.Use(func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (any, error) {
+			res, err := next(ctx, op)
+			seen = append(seen, err)
+			return res, err
+		}
+	})
+
+	car := Car{Brand: "Fiat"}
+	if err := repo.UpdateFields(context.Background(), &car); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("expected ErrNoChanges, got %v", err)
+	}
+	if len(seen) != 1 || !errors.Is(seen[0], ErrNoChanges) {
+		t.Errorf("expected the middleware to observe ErrNoChanges, got %v", seen)
+	}
+}
+
+func TestRepository_UseInheritedByTransactions(t *testing.T) {
+	repo := New(setupTestDB(t))
+	ctx := context.Background()
+	var log []string
+	repo.Use(recordOps("mw", &log))
+
+	err := repo.Transaction(ctx, func(txRepo *Repository) error {
+		return txRepo.Create(ctx, &Car{Brand: "Fiat"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	for item, err := range Iterate[Car](ctx, repo, nil) {
+		if err != nil || item.Brand != "Fiat" {
+			t.Fatalf("unexpected item %+v (err %v)", item, err)
+		}
+	}
+
+	want := []string{"mw:Create", "mw:Create:done", "mw:Iterate", "mw:Iterate:done"}
+	if !slices.Equal(log, want) {
+		t.Errorf("expected %v, got %v", want, log)
+	}
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryOption customises the query run by a find method (GetByID, GetAll, Find, Paginate, ...).
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	trashed  trashedScope
+	lock     LockMode
+	preloads []association
+	joins    []association
+	selects  []string
+	omits    []string
+	raw      bool
+	// DTO fields matching no column fail
+	strictDTO bool
+	// joins only filter the rows, without selecting the joined columns
+	bareJoins bool
+}
+
+// trashedScope selects which soft-deleted records a query sees.
+type trashedScope int
+
+const (
+	excludeTrashed trashedScope = iota
+	includeTrashed
+	onlyTrashed
+)
+
+// WithTrashed includes soft-deleted records in the results.
+func WithTrashed() QueryOption {
+	return func(o *queryOptions) {
+		o.trashed = includeTrashed
+	}
+}
+
+// OnlyTrashed restricts the results to soft-deleted records.
+// The model must embed gorm.DeletedAt, otherwise ErrSoftDeleteUnsupported is returned.
+func OnlyTrashed() QueryOption {
+	return func(o *queryOptions) {
+		o.trashed = onlyTrashed
+	}
+}
+
+// rawKey marks in the statement settings the queries reading raw values.
+const rawKey = "gormr:raw"
+
+// RawValues reads the values as stored: GORM plugins transforming the values read (e.g.
+// decryption) leave them untouched, see IsRawRead.
+func RawValues() QueryOption {
+	return func(o *queryOptions) {
+		o.raw = true
+	}
+}
+
+// IsRawRead reports whether the query run by db was given RawValues, for plugins transforming the
+// values read to skip it.
+func IsRawRead(db *gorm.DB) bool {
+	raw, _ := db.Get(rawKey)
+	return raw == true
+}
+
+// query starts a query on model with opts applied.
+func (r *Repository) query(ctx context.Context, model any, opts []QueryOption) (*gorm.DB, error) {
+	var o queryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	q := r.conn(ctx).Model(model)
+	if o.raw {
+		q = q.Set(rawKey, true)
+	}
+	switch o.trashed {
+	case includeTrashed:
+		q = q.Unscoped()
+	case onlyTrashed:
+		f, err := r.deletedAtField(model)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Unscoped().Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: nil})
+	}
+
+	if o.lock != 0 {
+		if err := o.lock.validate(); err != nil {
+			return nil, err
+		}
+		if r.activeTx(ctx) == nil {
+			return nil, ErrLockOutsideTransaction
+		}
+		s, err := r.schemaOf(model)
+		if err != nil {
+			return nil, err
+		}
+		q = applyLock(q, r.dialect(), o.lock, s.Table)
+	}
+	q = r.project(q, model, &o)
+
+	if len(o.preloads) > 0 || len(o.joins) > 0 {
+		s, err := r.schemaOf(model)
+		if err != nil {
+			return nil, err
+		}
+		return r.applyAssociations(q, s, &o)
+	}
+	return q, nil
+}
+
+// countQuery is query without row locks, which databases refuse on aggregates, nor preloads,
+// projections and joined columns, which have nothing to load into (and which databases refuse
+// next to GROUP BY).
+func (r *Repository) countQuery(ctx context.Context, model any, opts []QueryOption) (*gorm.DB, error) {
+	return r.query(ctx, model, append(opts[:len(opts):len(opts)], func(o *queryOptions) {
+		o.lock = 0
+		o.preloads = nil
+		o.selects, o.omits = nil, nil
+		o.bareJoins = true
+	}))
+}
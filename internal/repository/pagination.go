@@ -0,0 +1,86 @@
+package repository
+
+import "context"
+
+// PageRequest describes the page requested from Paginate.
+type PageRequest struct {
+	// Page number, starting at 1
+	Page int
+	// Number of items per page
+	PageSize int
+	// Upper bound for PageSize; larger values are clamped to it (0 means no limit)
+	MaxPageSize int
+	// Skip the COUNT query. Total and TotalPages are reported as -1 and HasNext is
+	// resolved by fetching one extra row.
+	SkipCount bool
+}
+
+// Page is a page of results together with its pagination metadata.
+type Page[T any] struct {
+	Items      []T
+	Page       int
+	PageSize   int
+	Total      int64
+	TotalPages int
+	HasNext    bool
+	HasPrev    bool
+}
+
+// Paginate finds the page of records matching spec described by req.
+// Unlike GetPaginated, an invalid page or page size returns ErrInvalidPage instead of every record.
+// When spec has no model, a *T is used.
+func Paginate[T any](ctx context.Context, r *Repository, spec *Spec, req PageRequest, opts ...QueryOption) (*Page[T], error) {
+	return call(ctx, r, OpPaginate, spec.modelOr(new(T)), []any{&spec, &req, &opts}, func(ctx context.Context) (*Page[T], error) {
+		return paginate[T](ctx, r, spec, req, opts)
+	})
+}
+
+// paginate is Paginate without the middleware chain.
+func paginate[T any](ctx context.Context, r *Repository, spec *Spec, req PageRequest, opts []QueryOption) (*Page[T], error) {
+	if req.Page <= 0 || req.PageSize <= 0 {
+		return nil, ErrInvalidPage
+	}
+	size := req.PageSize
+	if req.MaxPageSize > 0 && size > req.MaxPageSize {
+		size = req.MaxPageSize
+	}
+
+	page := &Page[T]{
+		Items:      []T{},
+		Page:       req.Page,
+		PageSize:   size,
+		Total:      -1,
+		TotalPages: -1,
+		HasPrev:    req.Page > 1,
+	}
+	q, err := r.query(ctx, spec.modelOr(new(T)), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.SkipCount {
+		cq, err := r.countQuery(ctx, spec.modelOr(new(T)), opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := spec.applyWhere(cq).Count(&page.Total).Error; err != nil {
+			return nil, err
+		}
+		page.TotalPages = int((page.Total + int64(size) - 1) / int64(size))
+		page.HasNext = req.Page < page.TotalPages
+	}
+
+	limit := size
+	if req.SkipCount {
+		limit++
+	}
+	offset := (req.Page - 1) * size
+	if err := spec.apply(q).Offset(offset).Limit(limit).Find(&page.Items).Error; err != nil {
+		return nil, err
+	}
+	if req.SkipCount && len(page.Items) > size {
+		page.Items = page.Items[:size]
+		page.HasNext = true
+	}
+	return page, nil
+}
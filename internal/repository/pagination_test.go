@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	seedCars(t, repo, carPageTestData)
+
+	for name, tt := range pageTestCases {
+		t.Run(name, func(t *testing.T) {
+			page, err := Paginate[Car](ctx, repo, tt.spec, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Paginate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Paginate failed: %v", err)
+			}
+			if len(page.Items) != tt.wantItems {
+				t.Errorf("expected %d items, got %d", tt.wantItems, len(page.Items))
+			}
+			if page.Total != tt.wantTotal || page.TotalPages != tt.wantPages || page.PageSize != tt.wantSize {
+				t.Errorf("got total=%d pages=%d size=%d, want total=%d pages=%d size=%d",
+					page.Total, page.TotalPages, page.PageSize, tt.wantTotal, tt.wantPages, tt.wantSize)
+			}
+			if page.HasNext != tt.wantNext || page.HasPrev != tt.wantPrev {
+				t.Errorf("got next=%v prev=%v, want next=%v prev=%v", page.HasNext, page.HasPrev, tt.wantNext, tt.wantPrev)
+			}
+		})
+	}
+}
+
+func TestCarRepository_FindAndCount(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	seedCars(t, repo, carByFieldTestData)
+
+	spec := NewSpec(&Car{}).Where("year >= ?", 2019).OrderBy("year desc")
+	var cars []Car
+	if err := repo.Find(ctx, spec, &cars); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(cars) != 2 || cars[0].Brand != "Toyota" {
+		t.Errorf("expected Toyota then Peugeot, got %+v", cars)
+	}
+
+	total, err := repo.Count(ctx, spec)
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("expected count 2, got %d", total)
+	}
+
+	if err := repo.Find(ctx, spec.Limit(1), &cars); err != nil {
+		t.Fatalf("Find with limit failed: %v", err)
+	}
+	if len(cars) != 1 || cars[0].Brand != "Toyota" {
+		t.Errorf("expected only Toyota, got %+v", cars)
+	}
+	if total, _ := repo.Count(ctx, spec); total != 2 {
+		t.Errorf("expected count to ignore the limit, got %d", total)
+	}
+}
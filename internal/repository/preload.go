@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// association is an association loaded by Preload or Joins.
+type association struct {
+	path  string
+	conds []any
+	inner bool
+}
+
+// Preload loads the association at path (e.g. "Orders" or "Orders.Lines") with a separate
+// query per level. conds filter the last association of path, like GORM's Preload: a query
+// with args, a struct or map, or func(*gorm.DB) *gorm.DB scopes (e.g. to order the
+// association). The last element may be clause.Associations to load every association of that
+// level. Paths are checked against the model schema: unknown associations fail with
+// ErrUnknownAssociation.
+func Preload(path string, conds ...any) QueryOption {
+	return func(o *queryOptions) {
+		o.preloads = append(o.preloads, association{path: path, conds: conds})
+	}
+}
+
+// Joins loads the has-one or belongs-to association at path (e.g. "Customer" or
+// "Order.Customer") in the same query with a LEFT JOIN; other associations fail with
+// ErrUnsupportedJoin. The joined table is aliased with the association name, so conds, added to
+// the ON clause, are best given as a struct or map, which are qualified with it; raw conditions
+// must reference the alias (e.g. `"Customer".name = ?`).
+func Joins(path string, conds ...any) QueryOption {
+	return func(o *queryOptions) {
+		o.joins = append(o.joins, association{path: path, conds: conds})
+	}
+}
+
+// InnerJoins is Joins with an INNER JOIN: records without the association are left out.
+func InnerJoins(path string, conds ...any) QueryOption {
+	return func(o *queryOptions) {
+		o.joins = append(o.joins, association{path: path, conds: conds, inner: true})
+	}
+}
+
+// applyAssociations validates the preloads and joins of o against s and adds them to q.
+func (r *Repository) applyAssociations(q *gorm.DB, s *schema.Schema, o *queryOptions) (*gorm.DB, error) {
+	for _, p := range o.preloads {
+		if err := checkAssociation(s, p.path, false); err != nil {
+			return nil, err
+		}
+		q = q.Preload(p.path, p.conds...)
+	}
+	for _, j := range o.joins {
+		if err := checkAssociation(s, j.path, true); err != nil {
+			return nil, err
+		}
+		var on *gorm.DB
+		if len(j.conds) > 0 {
+			var ok bool
+			if on, ok = j.conds[0].(*gorm.DB); !ok || len(j.conds) > 1 {
+				on = r.db.Session(&gorm.Session{NewDB: true}).Where(j.conds[0], j.conds[1:]...)
+			}
+		}
+		if o.bareJoins {
+			if on == nil {
+				on = r.db.Session(&gorm.Session{NewDB: true})
+			}
+			on = on.Session(&gorm.Session{}).Omit("*")
+		}
+		var args []any
+		if on != nil {
+			args = []any{on}
+		}
+		if j.inner {
+			q = q.InnerJoins(j.path, args...)
+		} else {
+			q = q.Joins(j.path, args...)
+		}
+	}
+	return q, nil
+}
+
+// checkAssociation checks every element of path names an association, of the kind supported
+// by joins when join is set.
+func checkAssociation(s *schema.Schema, path string, join bool) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if name == clause.Associations && !join && i == len(names)-1 {
+			return nil
+		}
+		rel, ok := s.Relationships.Relations[name]
+		if !ok {
+			return fmt.Errorf("%w: %s has no association %q (in %q)", ErrUnknownAssociation, s.Name, name, path)
+		}
+		if join && rel.Type != schema.HasOne && rel.Type != schema.BelongsTo {
+			return fmt.Errorf("%w: %s.%s is a %s association, use Preload", ErrUnsupportedJoin, s.Name, name, rel.Type)
+		}
+		s = rel.FieldSchema
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func setupLibrary(t *testing.T) *Repository {
+	t.Helper()
+	repo := New(setupTestDB(t, &Author{}, &Book{}, &Review{}))
+	ctx := context.Background()
+	authors := []*Author{
+		{Name: "Le Guin", Books: []Book{
+			{Title: "The Dispossessed", Reviews: []Review{{Stars: 5}, {Stars: 3}}},
+			{Title: "Earthsea"},
+		}},
+		{Name: "Lem", Books: []Book{{Title: "Solaris", Reviews: []Review{{Stars: 4}}}}},
+		{Name: "Nobody"},
+	}
+	for _, a := range authors {
+		if err := repo.Create(ctx, a); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestRepository_Preload(t *testing.T) {
+	repo := setupLibrary(t)
+	ctx := context.Background()
+
+	var authors []Author
+	if err := repo.GetAll(ctx, &Author{}, &authors, Preload("Books.Reviews")); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(authors) != 3 || len(authors[0].Books) != 2 || len(authors[0].Books[0].Reviews) != 2 {
+		t.Fatalf("expected nested associations to be loaded, got %+v", authors)
+	}
+
+	// Conditions apply to the last association of the path
+	var author Author
+	err := repo.GetByID(ctx, &Author{}, authors[0].ID, &author,
+		Preload("Books", "title <> ?", "Earthsea"),
+		Preload("Books.Reviews", func(db *gorm.DB) *gorm.DB { return db.Where("stars > ?", 4) }))
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if len(author.Books) != 1 || len(author.Books[0].Reviews) != 1 || author.Books[0].Reviews[0].Stars != 5 {
+		t.Errorf("expected filtered associations, got %+v", author)
+	}
+
+	var books []Book
+	if err := repo.Find(ctx, NewSpec(&Book{}).Eq("title", "Solaris"), &books, Preload(clause.Associations)); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(books) != 1 || books[0].Author == nil || len(books[0].Reviews) != 1 {
+		t.Errorf("expected every association to be loaded, got %+v", books)
+	}
+
+	// Counts ignore preloads
+	page, err := Paginate[Author](ctx, repo, nil, PageRequest{Page: 1, PageSize: 2}, Preload("Books"))
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if page.Total != 3 || len(page.Items[0].Books) != 2 {
+		t.Errorf("expected 3 authors with their books, got %+v", page)
+	}
+}
+
+func TestRepository_Joins(t *testing.T) {
+	repo := setupLibrary(t)
+	ctx := context.Background()
+
+	var reviews []Review
+	if err := repo.GetAll(ctx, &Review{}, &reviews, Joins("Book.Author")); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(reviews) != 3 || reviews[0].Book == nil || reviews[0].Book.Author == nil || reviews[0].Book.Author.Name != "Le Guin" {
+		t.Fatalf("expected nested joins to be loaded, got %+v", reviews)
+	}
+
+	var books []Book
+	if err := repo.Find(ctx, NewSpec(&Book{}).OrderBy("books.id"), &books, Joins("Author", &Author{Name: "Lem"})); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(books) != 3 || books[0].Author != nil || books[2].Author == nil {
+		t.Errorf("expected a left join loading only Lem, got %+v", books)
+	}
+	n, err := repo.Count(ctx, NewSpec(&Book{}), InnerJoins("Author", map[string]any{"name": "Lem"}))
+	if err != nil || n != 1 {
+		t.Errorf("expected 1 book of Lem, got %d (%v)", n, err)
+	}
+
+	var streamed []Book
+	for b, err := range Iterate[Book](ctx, repo, NewSpec(&Book{}), Joins("Author")) {
+		if err != nil {
+			t.Fatalf("Iterate failed: %v", err)
+		}
+		streamed = append(streamed, b)
+	}
+	if len(streamed) != 3 || streamed[0].Author == nil || streamed[0].Author.Name != "Le Guin" {
+		t.Errorf("expected joined associations when iterating, got %+v", streamed)
+	}
+}
+
+func TestRepository_AssociationErrors(t *testing.T) {
+	repo := setupLibrary(t)
+	ctx := context.Background()
+	var books []Book
+
+	for _, opt := range []QueryOption{Preload("Publisher"), Preload("Reviews.Author"), Joins("Writer")} {
+		if err := repo.GetAll(ctx, &Book{}, &books, opt); !errors.Is(err, ErrUnknownAssociation) {
+			t.Errorf("expected ErrUnknownAssociation, got %v", err)
+		}
+	}
+	if err := repo.GetAll(ctx, &Book{}, &books, Joins("Reviews")); !errors.Is(err, ErrUnsupportedJoin) {
+		t.Errorf("expected ErrUnsupportedJoin joining a has-many association, got %v", err)
+	}
+	for _, err := range Iterate[Book](ctx, repo, nil, Preload("Reviews")) {
+		if !errors.Is(err, ErrUnsupportedPreload) {
+			t.Errorf("expected Iterate to reject Preload with ErrUnsupportedPreload, got %v", err)
+		}
+	}
+}
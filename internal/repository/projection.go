@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Select restricts the columns read by a find to columns: field or column names of the model, or
+// SQL expressions (e.g. "upper(name) as name"). Columns not read are left zero.
+func Select(columns ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.selects = append(o.selects, columns...)
+	}
+}
+
+// Omit leaves columns (field or column names of the model) out of the columns read by a find,
+// e.g. large blobs in list endpoints.
+func Omit(columns ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.omits = append(o.omits, columns...)
+	}
+}
+
+// StrictDTO makes finds scanning into a DTO fail with ErrUnmappedField when a DTO field matches no
+// column of the model, instead of leaving it zero.
+func StrictDTO() QueryOption {
+	return func(o *queryOptions) {
+		o.strictDTO = true
+	}
+}
+
+// project adds the Select and Omit options of o to q. Finds scanning into a struct other than the
+// model (a DTO) read the model columns matching the DTO fields, by column name (set them with
+// `gorm:"column:..."`), unless columns are selected (Select, aggregations): DTO fields matching
+// no column (e.g. computed ones) are left zero, or fail with ErrUnmappedField given StrictDTO.
+func (r *Repository) project(q *gorm.DB, model any, o *queryOptions) *gorm.DB {
+	if len(o.selects) > 0 {
+		q = q.Select(o.selects)
+	}
+	return q.Scopes(func(db *gorm.DB) *gorm.DB {
+		if len(db.Statement.Selects) > 0 {
+			return db
+		}
+		dto := destType(db.Statement.Dest)
+		if dto == nil {
+			return db.Omit(o.omits...)
+		}
+		s, err := r.schemaOf(model)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		if dto == s.ModelType {
+			return db.Omit(o.omits...)
+		}
+		cols, err := r.dtoColumns(s, dto, o)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		return db.Clauses(clause.Select{Columns: cols})
+	})
+}
+
+// dtoColumns returns the columns of the model s matching the fields of dto, but the omitted ones.
+func (r *Repository) dtoColumns(s *schema.Schema, dto reflect.Type, o *queryOptions) ([]clause.Column, error) {
+	d, err := r.schemaOf(reflect.New(dto).Interface())
+	if err != nil {
+		return nil, err
+	}
+	omitted := func(f *schema.Field) bool {
+		return slices.Contains(o.omits, f.Name) || slices.Contains(o.omits, f.DBName)
+	}
+	var cols []clause.Column
+	for _, name := range d.DBNames {
+		f := s.LookUpField(name)
+		if f == nil && !o.strictDTO {
+			continue
+		}
+		if f == nil {
+			return nil, fmt.Errorf("%w: %s.%s matches no column of %s", ErrUnmappedField, d.Name, d.FieldsByDBName[name].Name, s.Name)
+		}
+		if !omitted(f) {
+			cols = append(cols, clause.Column{Table: clause.CurrentTable, Name: f.DBName})
+		}
+	}
+	return cols, nil
+}
+
+// destType returns the struct type scanned by a find into dest (a struct, or a slice of structs
+// or pointers to them), nil for other destinations (e.g. counts).
+func destType(dest any) reflect.Type {
+	if dest == nil {
+		return nil
+	}
+	t := reflect.TypeOf(dest)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
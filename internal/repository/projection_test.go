@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func setupDocuments(t *testing.T) *Repository {
+	t.Helper()
+	repo := New(setupTestDB(t, &Document{}))
+	for _, d := range []*Document{
+		{Title: "Spec", Owner: "ann", Body: []byte("long spec")},
+		{Title: "Notes", Owner: "bob", Body: []byte("long notes")},
+	} {
+		if err := repo.Create(context.Background(), d); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestRepository_SelectOmit(t *testing.T) {
+	repo := setupDocuments(t)
+	ctx := context.Background()
+
+	var docs []Document
+	if err := repo.GetAll(ctx, &Document{}, &docs, Omit("Body")); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(docs) != 2 || docs[0].Title != "Spec" || docs[0].Owner != "ann" || docs[0].Body != nil {
+		t.Errorf("expected every column but body, got %+v", docs)
+	}
+
+	docs = nil
+	if err := repo.GetByField(ctx, &Document{}, "owner", "bob", &docs, Select("id", "Title", "upper(owner) as owner")); err != nil {
+		t.Fatalf("GetByField failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Title != "Notes" || docs[0].Owner != "BOB" || docs[0].Body != nil {
+		t.Errorf("expected the selected columns, got %+v", docs)
+	}
+
+	page, err := Paginate[Document](ctx, repo, nil, PageRequest{Page: 1, PageSize: 1}, Select("title"))
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if page.Total != 2 || page.Items[0].Title != "Spec" || page.Items[0].ID != 0 {
+		t.Errorf("expected a page of titles, got %+v", page)
+	}
+}
+
+func TestRepository_DTO(t *testing.T) {
+	repo := setupDocuments(t)
+	ctx := context.Background()
+	var captured []string
+	err := repo.db.Callback().Query().After("gorm:query").Register("test:capture", func(db *gorm.DB) {
+		captured = append(captured, db.Statement.SQL.String())
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	var summaries []DocumentSummary
+	if err := repo.Find(ctx, NewSpec(&Document{}).OrderBy("id"), &summaries); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(summaries) != 2 || summaries[0].ID == 0 || summaries[0].Heading != "Spec" {
+		t.Errorf("expected summaries, got %+v", summaries)
+	}
+	if sql := captured[len(captured)-1]; strings.Contains(sql, "body") || strings.Contains(sql, "owner") {
+		t.Errorf("expected only the DTO columns to be read, got %s", sql)
+	}
+
+	var one DocumentSummary
+	if err := repo.GetByID(ctx, &Document{}, summaries[1].ID, &one, Omit("id")); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if one.ID != 0 || one.Heading != "Notes" {
+		t.Errorf("expected the DTO without id, got %+v", one)
+	}
+
+	var batches int
+	err = FindInBatches(ctx, repo, NewSpec(&Document{}), 1, func(batch []*DocumentSummary) error {
+		batches++
+		if len(batch) != 1 || batch[0].Heading == "" {
+			t.Errorf("expected a summary per batch, got %+v", batch)
+		}
+		return nil
+	})
+	if err != nil || batches != 2 {
+		t.Errorf("expected 2 batches, got %d (%v)", batches, err)
+	}
+
+	type badSummary struct {
+		ID    uint
+		Pages int
+	}
+	var bad []badSummary
+	if err := repo.GetAll(ctx, &Document{}, &bad); err != nil || len(bad) != 2 || bad[0].ID == 0 || bad[0].Pages != 0 {
+		t.Errorf("expected the unmapped field to be left zero, got %+v (%v)", bad, err)
+	}
+	var single badSummary
+	if err := repo.GetByField(ctx, &Document{}, "owner", "bob", &single); err != nil || single.ID == 0 {
+		t.Errorf("expected GetByField to load the DTO, got %+v (%v)", single, err)
+	}
+	if err := repo.GetAll(ctx, &Document{}, &bad, StrictDTO()); !errors.Is(err, ErrUnmappedField) {
+		t.Errorf("expected ErrUnmappedField with StrictDTO, got %v", err)
+	}
+	if err := repo.GetAll(ctx, &Document{}, &bad, Select("id", "length(body) as pages")); err != nil || bad[0].Pages != 9 {
+		t.Errorf("expected Select to bypass the DTO columns, got %+v (%v)", bad, err)
+	}
+}
@@ -5,60 +5,159 @@ import (
 	"fmt"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository is a thin generic repository that works with any models.
 // It provides CRUD, pagination, queries by field and transaction composition.
 type Repository struct {
 	db *gorm.DB
+	// root is the connection independent transactions are started on; txRepos keep their parent's.
+	root *gorm.DB
+	// middlewares wrap every data operation, see Use
+	middlewares []Middleware
 }
 
 // New creates a Repository bound to the provided *gorm.DB (or a tx).
 func New(db *gorm.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: db, root: db}
+}
+
+// AutoMigrate creates or updates the tables of the given models.
+func (r *Repository) AutoMigrate(ctx context.Context, models ...any) error {
+	return r.conn(ctx).AutoMigrate(models...)
 }
 
 // Create inserts the given entity into DB.
 func (r *Repository) Create(ctx context.Context, entity any) error {
-	return r.db.WithContext(ctx).Create(entity).Error
+	return r.do(ctx, OpCreate, entity, []any{&entity}, func(ctx context.Context) error {
+		return r.conn(ctx).Create(entity).Error
+	})
 }
 
 // Update saves the provided entity.
+// Versioned entities are only written if their version is unchanged in DB, otherwise ErrStaleObject is returned.
 func (r *Repository) Update(ctx context.Context, entity any) error {
-	return r.db.WithContext(ctx).Save(entity).Error
+	return r.do(ctx, OpUpdate, entity, []any{&entity}, func(ctx context.Context) error {
+		return r.update(ctx, entity)
+	})
+}
+
+// update is Update without the middleware chain.
+func (r *Repository) update(ctx context.Context, entity any) error {
+	vf, err := r.versionField(entity)
+	if err != nil {
+		return err
+	}
+	if vf == nil {
+		return r.conn(ctx).Save(entity).Error
+	}
+	isNew, err := r.isNew(ctx, entity)
+	if err != nil {
+		return err
+	}
+	if isNew {
+		return r.conn(ctx).Create(entity).Error
+	}
+	return r.updateVersioned(ctx, entity, vf, func(q *gorm.DB, _ int64) *gorm.DB {
+		return q.Select("*").Updates(entity)
+	})
 }
 
 // Delete deletes the provided entity (or by primary key if entity is a model with ID set).
+// Versioned entities are only deleted if their version is unchanged in DB, otherwise ErrStaleObject is returned.
 func (r *Repository) Delete(ctx context.Context, entity any) error {
-	return r.db.WithContext(ctx).Delete(entity).Error
+	return r.do(ctx, OpDelete, entity, []any{&entity}, func(ctx context.Context) error {
+		return r.delete(ctx, entity)
+	})
+}
+
+// delete is Delete without the middleware chain.
+func (r *Repository) delete(ctx context.Context, entity any) error {
+	vf, err := r.versionField(entity)
+	if err != nil {
+		return err
+	}
+	if vf != nil {
+		return r.deleteVersioned(ctx, entity, vf)
+	}
+	return r.conn(ctx).Delete(entity).Error
 }
 
 // DeleteByID deletes a model by primary key value.
 func (r *Repository) DeleteByID(ctx context.Context, model any, id any) error {
-	return r.db.WithContext(ctx).Delete(model, id).Error
+	return r.do(ctx, OpDeleteByID, model, []any{&model, &id}, func(ctx context.Context) error {
+		return r.conn(ctx).Delete(model, idCondition(id)).Error
+	})
+}
+
+// idCondition returns the GORM condition matching the primary key to id. String ids (e.g. UUIDs)
+// are turned into an explicit condition, GORM would take them for SQL otherwise.
+func idCondition(id any) any {
+	if s, ok := id.(string); ok {
+		return clause.Eq{Column: clause.PrimaryColumn, Value: s}
+	}
+	return id
+}
+
+// DeleteWhere deletes every record matching spec and returns the number of deleted rows.
+// A spec without conditions is rejected with gorm.ErrMissingWhereClause.
+func (r *Repository) DeleteWhere(ctx context.Context, spec *Spec) (int64, error) {
+	return call(ctx, r, OpDeleteWhere, spec.Model(), []any{&spec}, func(ctx context.Context) (int64, error) {
+		res := spec.applyWhere(r.conn(ctx)).Delete(spec.Model())
+		return res.RowsAffected, res.Error
+	})
 }
 
 // GetByID finds a single record by primary key. Returns (nil, nil) when not found.
-func (r *Repository) GetByID(ctx context.Context, model any, id any, out any) error {
-	if err := r.db.WithContext(ctx).Model(model).First(out, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil
+func (r *Repository) GetByID(ctx context.Context, model any, id any, out any, opts ...QueryOption) error {
+	return r.do(ctx, OpGetByID, model, []any{&model, &id, &out, &opts}, func(ctx context.Context) error {
+		q, err := r.query(ctx, model, opts)
+		if err != nil {
+			return err
 		}
-		return err
-	}
-	return nil
+		if err := q.First(out, idCondition(id)).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
 }
 
 // GetAll finds all records for model and scans into out.
-func (r *Repository) GetAll(ctx context.Context, model any, out any) error {
-	return r.db.WithContext(ctx).Model(model).Find(out).Error
+func (r *Repository) GetAll(ctx context.Context, model any, out any, opts ...QueryOption) error {
+	return r.do(ctx, OpGetAll, model, []any{&model, &out, &opts}, func(ctx context.Context) error {
+		q, err := r.query(ctx, model, opts)
+		if err != nil {
+			return err
+		}
+		return q.Find(out).Error
+	})
 }
 
 // GetPaginated finds records with offset/limit and scans into out.
-func (r *Repository) GetPaginated(ctx context.Context, model any, out any, page, pageSize int) (int64, error) {
+// It returns every record when page or pageSize <= 0; see Paginate for page metadata and validation.
+func (r *Repository) GetPaginated(ctx context.Context, model any, out any, page, pageSize int, opts ...QueryOption) (int64, error) {
+	params := []any{&model, &out, &page, &pageSize, &opts}
+	return call(ctx, r, OpGetPaginated, model, params, func(ctx context.Context) (int64, error) {
+		return r.getPaginated(ctx, model, out, page, pageSize, opts)
+	})
+}
+
+// getPaginated is GetPaginated without the middleware chain.
+func (r *Repository) getPaginated(ctx context.Context, model any, out any, page, pageSize int, opts []QueryOption) (int64, error) {
 	var total int64
-	q := r.db.WithContext(ctx).Model(model)
-	if err := q.Count(&total).Error; err != nil {
+	cq, err := r.countQuery(ctx, model, opts)
+	if err != nil {
+		return 0, err
+	}
+	if err := cq.Count(&total).Error; err != nil {
+		return 0, err
+	}
+	q, err := r.query(ctx, model, opts)
+	if err != nil {
 		return 0, err
 	}
 	if page <= 0 || pageSize <= 0 {
@@ -77,24 +176,17 @@ func (r *Repository) GetPaginated(ctx context.Context, model any, out any, page,
 // GetByField finds records where field = value and scans into out.
 // model: a pointer to the model type or model instance for GORM's Model()
 // field: column name (e.g. "key" or "email")
-// value: value to match
-func (r *Repository) GetByField(ctx context.Context, model any, field string, value any, out any) error {
-	cond := fmt.Sprintf("%s = ?", field)
-	return r.db.WithContext(ctx).Model(model).Where(cond, value).Find(out).Error
-}
-
-// Transaction runs the provided function inside a transaction. Commit is automatic when fn returns nil,
-// rollback if fn returns an error. The txRepo provided uses the transactional *gorm.DB.
-func (r *Repository) Transaction(ctx context.Context, fn func(txRepo *Repository) error) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		txRepo := New(tx)
-		return fn(txRepo)
+// value: value to match, or a []any matching any of its values
+func (r *Repository) GetByField(ctx context.Context, model any, field string, value any, out any, opts ...QueryOption) error {
+	return r.do(ctx, OpGetByField, model, []any{&model, &field, &value, &out, &opts}, func(ctx context.Context) error {
+		q, err := r.query(ctx, model, opts)
+		if err != nil {
+			return err
+		}
+		cond := fmt.Sprintf("%s = ?", field)
+		if _, ok := value.([]any); ok {
+			cond = fmt.Sprintf("%s IN ?", field)
+		}
+		return q.Where(cond, value).Find(out).Error
 	})
 }
-
-// ManualTx returns a started transaction (*gorm.DB) so the caller can control Commit/Rollback.
-// Caller must call tx.Commit() or tx.Rollback().
-func (r *Repository) ManualTx(ctx context.Context) (*gorm.DB, error) {
-	tx := r.db.WithContext(ctx).Begin()
-	return tx, tx.Error
-}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"testing"
 
 	"gorm.io/driver/sqlite"
@@ -30,6 +31,27 @@ func TestCarRepository_DeleteByID(t *testing.T) {
 	}
 }
 
+func TestCarRepository_DeleteWhere(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	seedCars(t, repo, carByFieldTestData)
+
+	n, err := repo.DeleteWhere(ctx, NewSpec(&Car{}).Where("year < ?", 2019))
+	if err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	left, _ := repo.Count(ctx, NewSpec(&Car{}))
+	if n != int64(len(carByFieldTestData))-2 || left != 2 {
+		t.Errorf("expected only the 2 recent cars left, deleted %d and kept %d", n, left)
+	}
+
+	if _, err := repo.DeleteWhere(ctx, NewSpec(&Car{})); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("expected ErrMissingWhereClause without conditions, got %v", err)
+	}
+}
+
 func TestCarRepository_GetByID(t *testing.T) {
 	db := setupTestDB(t)
 	repo := New(db)
@@ -50,6 +72,27 @@ func TestCarRepository_GetByID(t *testing.T) {
 	}
 }
 
+func TestPlateRepository_StringID(t *testing.T) {
+	db := setupTestDB(t, &Plate{})
+	repo := New(db)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &Plate{Number: "AB-123", Region: "North"}); err != nil {
+		t.Fatalf("failed to create plate: %v", err)
+	}
+	var got Plate
+	if err := repo.GetByID(ctx, &Plate{}, "AB-123", &got); err != nil || got.Region != "North" {
+		t.Fatalf("GetByID failed: %+v (%v)", got, err)
+	}
+	if err := repo.DeleteByID(ctx, &Plate{}, "AB-123"); err != nil {
+		t.Fatalf("DeleteByID failed: %v", err)
+	}
+	got = Plate{}
+	if err := repo.GetByID(ctx, &Plate{}, "AB-123", &got); err != nil || got.Number != "" {
+		t.Errorf("expected plate to be deleted, got %+v (%v)", got, err)
+	}
+}
+
 func TestCarRepository_GetAll(t *testing.T) {
 	db := setupTestDB(t)
 	repo := New(db)
@@ -114,6 +157,13 @@ func TestCarRepository_GetByField(t *testing.T) {
 	if len(cars) != 1 || cars[0].Brand != "Peugeot" {
 		t.Errorf("expected 1 Peugeot, got %+v", cars)
 	}
+
+	if err := repo.GetByField(ctx, &Car{}, "brand", []any{"Ford", "Toyota"}, &cars); err != nil {
+		t.Fatalf("GetByField failed: %v", err)
+	}
+	if len(cars) != 2 {
+		t.Errorf("expected the Ford and the Toyota, got %+v", cars)
+	}
 }
 
 func TestCarRepository_Transaction(t *testing.T) {
@@ -240,13 +290,40 @@ func TestCarRepository_Delete(t *testing.T) {
 	}
 }
 
-func setupTestDB(t *testing.T) *gorm.DB {
+func setupTestDB(t *testing.T, models ...any) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("failed to open test db: %v", err)
 	}
-	if err := db.AutoMigrate(&Car{}); err != nil {
+	if err := db.AutoMigrate(append([]any{&Car{}}, models...)...); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 	return db
 }
+
+// setupFileTestDB opens a file-backed SQLite DB, for tests needing several connections.
+func setupFileTestDB(t *testing.T, models ...any) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(append([]any{&Car{}}, models...)...); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func seedCars(t *testing.T, repo *Repository, cars []Car) {
+	t.Helper()
+	for _, c := range cars {
+		car := c
+		if err := repo.Create(context.Background(), &car); err != nil {
+			t.Fatalf("failed to create car: %v", err)
+		}
+	}
+}
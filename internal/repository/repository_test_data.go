@@ -1,5 +1,7 @@
 package repository
 
+import "gorm.io/gorm"
+
 // Car model and test cases for repository tests
 type Car struct {
 	ID    uint
@@ -48,3 +50,179 @@ var carByFieldTestData = []Car{
 	{Brand: "Peugeot", Color: "White", Year: 2019, Model: "208"},
 	{Brand: "Ford", Color: "Blue", Year: 2018, Model: "Focus"},
 }
+
+// Data for Paginate
+var carPageTestData = []Car{
+	{Brand: "Toyota", Color: "Red", Year: 2020, Model: "Corolla"},
+	{Brand: "Ford", Color: "Blue", Year: 2018, Model: "Focus"},
+	{Brand: "Peugeot", Color: "White", Year: 2019, Model: "208"},
+	{Brand: "Nissan", Color: "Green", Year: 2017, Model: "Sentra"},
+	{Brand: "Mazda", Color: "Gray", Year: 2019, Model: "3"},
+}
+
+// pageTestCase defines a Paginate test case over carPageTestData.
+type pageTestCase struct {
+	spec      *Spec
+	req       PageRequest
+	wantItems int
+	wantTotal int64
+	wantPages int
+	wantSize  int
+	wantNext  bool
+	wantPrev  bool
+	wantErr   error
+}
+
+var pageTestCases = map[string]pageTestCase{
+	"first_page": {
+		req:       PageRequest{Page: 1, PageSize: 2},
+		wantItems: 2, wantTotal: 5, wantPages: 3, wantSize: 2, wantNext: true,
+	},
+	"last_page": {
+		req:       PageRequest{Page: 3, PageSize: 2},
+		wantItems: 1, wantTotal: 5, wantPages: 3, wantSize: 2, wantPrev: true,
+	},
+	"clamped_page_size": {
+		req:       PageRequest{Page: 1, PageSize: 50, MaxPageSize: 4},
+		wantItems: 4, wantTotal: 5, wantPages: 2, wantSize: 4, wantNext: true,
+	},
+	"skip_count": {
+		req:       PageRequest{Page: 2, PageSize: 2, SkipCount: true},
+		wantItems: 2, wantTotal: -1, wantPages: -1, wantSize: 2, wantNext: true, wantPrev: true,
+	},
+	"skip_count_last_page": {
+		req:       PageRequest{Page: 3, PageSize: 2, SkipCount: true},
+		wantItems: 1, wantTotal: -1, wantPages: -1, wantSize: 2, wantPrev: true,
+	},
+	"filtered": {
+		spec:      NewSpec(&Car{}).Eq("year", 2019).OrderBy("brand"),
+		req:       PageRequest{Page: 1, PageSize: 1},
+		wantItems: 1, wantTotal: 2, wantPages: 2, wantSize: 1, wantNext: true,
+	},
+	"invalid_page": {
+		req:     PageRequest{Page: 0, PageSize: 2},
+		wantErr: ErrInvalidPage,
+	},
+	"invalid_page_size": {
+		req:     PageRequest{Page: 1},
+		wantErr: ErrInvalidPage,
+	},
+}
+
+// upsertResultTestCase defines how affected rows are split per dialect.
+type upsertResultTestCase struct {
+	dialect   string
+	n         int64
+	affected  int64
+	doNothing bool
+	want      UpsertResult
+}
+
+var upsertResultTestCases = map[string]upsertResultTestCase{
+	"mysql_do_nothing": {
+		dialect: "mysql", n: 3, affected: 1, doNothing: true,
+		want: UpsertResult{RowsAffected: 1, Inserted: 1, Skipped: 2, Exact: true},
+	},
+	"mysql_update": {
+		dialect: "mysql", n: 3, affected: 5,
+		want: UpsertResult{RowsAffected: 5, Inserted: 1, Updated: 2, Exact: true},
+	},
+	"mysql_insert_only": {
+		dialect: "mysql", n: 2, affected: 2,
+		want: UpsertResult{RowsAffected: 2, Inserted: 2, Exact: true},
+	},
+	"sqlite_update": {
+		dialect: "sqlite", n: 3, affected: 3,
+		want: UpsertResult{RowsAffected: 3},
+	},
+	"postgres_do_nothing": {
+		dialect: "postgres", n: 3, affected: 2, doNothing: true,
+		want: UpsertResult{RowsAffected: 2},
+	},
+}
+
+// Part is a model using optimistic locking through a version tag.
+type Part struct {
+	ID      uint
+	Name    string
+	Stock   int
+	Version int `gormr:"version"`
+}
+
+// Engine is a model using optimistic locking through the Versioned interface.
+type Engine struct {
+	ID       uint
+	Code     string
+	Revision uint
+}
+
+func (Engine) VersionField() string { return "Revision" }
+
+// Dealer is a model supporting soft deletes.
+type Dealer struct {
+	ID        uint
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+// Customer, Order and OrderLine form a chain of belongs-to relations for unit of work tests.
+type Customer struct {
+	ID   uint
+	Name string
+}
+
+type Order struct {
+	ID         uint
+	CustomerID uint
+	Customer   *Customer
+	Total      int
+}
+
+type OrderLine struct {
+	ID      uint
+	OrderID uint
+	Order   *Order
+	Product string
+}
+
+// Plate is a model with a string primary key.
+type Plate struct {
+	Number string `gorm:"primaryKey"`
+	Region string
+}
+
+// Author, Book and Review form has-many and belongs-to associations for preload tests.
+type Author struct {
+	ID    uint
+	Name  string
+	Books []Book
+}
+
+type Book struct {
+	ID       uint
+	AuthorID uint
+	Author   *Author
+	Title    string
+	Reviews  []Review
+}
+
+type Review struct {
+	ID     uint
+	BookID uint
+	Book   *Book
+	Stars  int
+}
+
+// Document has a large column left out of projections in tests.
+type Document struct {
+	ID    uint
+	Title string
+	Owner string
+	Body  []byte
+}
+
+// DocumentSummary is a DTO of Document.
+type DocumentSummary struct {
+	ID      uint
+	Heading string `gorm:"column:title"`
+}
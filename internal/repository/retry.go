@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Error codes classified as retryable.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	mysqlDeadlock          = 1213
+	mysqlLockWaitTimeout   = 1205
+	sqlserverDeadlock      = 1205
+)
+
+// RetryError is returned when a transaction still fails with a retryable error after all retries.
+type RetryError struct {
+	// Number of times the transaction was run
+	Attempts int
+	// Error of the last attempt
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("gormr: transaction failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Backoff returns how long to wait before retry number attempt (starting at 1).
+type Backoff func(attempt int) time.Duration
+
+// ExponentialBackoff doubles base on every retry, up to maxDelay, and picks a random wait
+// in the upper half of that delay so concurrent retries spread out.
+func ExponentialBackoff(base, maxDelay time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := maxDelay
+		if attempt < 32 {
+			if exp := base << (attempt - 1); exp > 0 && exp < maxDelay {
+				d = exp
+			}
+		}
+		half := d / 2
+		return half + rand.N(d-half+1)
+	}
+}
+
+// IsRetryable reports whether err is a transient concurrency failure for which the whole
+// transaction can be retried: Postgres serialization failures (40001) and deadlocks (40P01),
+// MySQL deadlocks (1213) and lock wait timeouts (1205), SQL Server deadlock victims (1205) and
+// SQLite busy/locked errors.
+func IsRetryable(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		code := pgErr.SQLState()
+		return code == pgSerializationFailure || code == pgDeadlockDetected
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout
+	}
+	var sqlserverErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &sqlserverErr) {
+		return sqlserverErr.SQLErrorNumber() == sqlserverDeadlock
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// retry calls attempt until it succeeds, fails with a non retryable error or runs out of retries.
+func (c txConfig) retry(ctx context.Context, attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if n > c.maxRetries {
+			if c.maxRetries == 0 {
+				return err
+			}
+			return &RetryError{Attempts: n, Err: err}
+		}
+		if c.onRetry != nil {
+			c.onRetry(n, err)
+		}
+
+		timer := time.NewTimer(c.backoff(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+// pgStateError mimics pgconn.PgError, which exposes its SQLSTATE through SQLState().
+type pgStateError string
+
+func (e pgStateError) Error() string    { return "pg: " + string(e) }
+func (e pgStateError) SQLState() string { return string(e) }
+
+// sqlserverNumberError mimics mssql.Error, which exposes its number through SQLErrorNumber().
+type sqlserverNumberError int32
+
+func (e sqlserverNumberError) Error() string         { return fmt.Sprintf("mssql: %d", int32(e)) }
+func (e sqlserverNumberError) SQLErrorNumber() int32 { return int32(e) }
+
+func noBackoff(int) time.Duration { return 0 }
+
+func TestIsRetryable(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"pg_serialization":   {err: pgStateError("40001"), want: true},
+		"pg_deadlock":        {err: fmt.Errorf("wrapped: %w", pgStateError("40P01")), want: true},
+		"pg_unique":          {err: pgStateError("23505"), want: false},
+		"mysql_deadlock":     {err: &mysql.MySQLError{Number: 1213}, want: true},
+		"mysql_duplicate":    {err: &mysql.MySQLError{Number: 1062}, want: false},
+		"sqlserver_deadlock": {err: sqlserverNumberError(1205), want: true},
+		"sqlserver_other":    {err: sqlserverNumberError(2627), want: false},
+		"sqlite_busy":        {err: sqlite3.Error{Code: sqlite3.ErrBusy}, want: true},
+		"plain":              {err: errors.New("boom"), want: false},
+		"nil":                {err: nil, want: false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCarRepository_TransactionRetry(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	calls := 0
+	var retried []int
+	err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		calls++
+		if err := repo.Create(ctx, &Car{Brand: "Toyota"}); err != nil {
+			return err
+		}
+		if calls < 3 {
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		}
+		return nil
+	}, WithRetry(3, noBackoff), OnRetry(func(attempt int, _ error) {
+		retried = append(retried, attempt)
+	}))
+	if err != nil {
+		t.Fatalf("RunInTransaction failed: %v", err)
+	}
+	if calls != 3 || len(retried) != 2 {
+		t.Errorf("expected 3 calls and 2 retries, got %d calls and retries %v", calls, retried)
+	}
+
+	total, err := repo.Count(ctx, NewSpec(&Car{}))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("expected failed attempts to be rolled back, got %d cars", total)
+	}
+}
+
+func TestCarRepository_TransactionRetryExhausted(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	calls := 0
+	err := repo.Transaction(ctx, func(*Repository) error {
+		calls++
+		return pgStateError("40001")
+	}, WithRetry(2, noBackoff))
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected *RetryError, got %v", err)
+	}
+	if retryErr.Attempts != 3 || calls != 3 {
+		t.Errorf("expected 3 attempts, got %d (calls %d)", retryErr.Attempts, calls)
+	}
+	if !IsRetryable(err) {
+		t.Error("expected the last attempt error to be wrapped")
+	}
+}
+
+func TestCarRepository_TransactionNoRetryOnOtherErrors(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	errFail := errors.New("fail")
+
+	calls := 0
+	err := repo.RunInTransaction(ctx, func(context.Context) error {
+		calls++
+		return errFail
+	}, WithRetry(5, noBackoff), ReadOnly())
+	if !errors.Is(err, errFail) || calls != 1 {
+		t.Errorf("expected a single failing call, got %d calls and %v", calls, err)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	for attempt, maxWant := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 10: 50 * time.Millisecond, 100: 50 * time.Millisecond} {
+		got := backoff(attempt)
+		if got < maxWant/2 || got > maxWant {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", attempt, got, maxWant/2, maxWant)
+		}
+	}
+}
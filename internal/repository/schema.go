@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// tagName is the struct tag holding gormr settings, e.g. `gormr:"version"`.
+// Settings are separated by ';' and may carry a value, like GORM's own tag.
+const tagName = "gormr"
+
+// Schema returns the GORM schema of model, for middlewares and plugins needing column metadata.
+func (r *Repository) Schema(model any) (*schema.Schema, error) {
+	return r.schemaOf(model)
+}
+
+// schemaOf parses the GORM schema of model (a struct, pointer or slice of them).
+func (r *Repository) schemaOf(model any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// tagSettings returns the gormr settings of field, keyed in upper case.
+func tagSettings(field *schema.Field) map[string]string {
+	return schema.ParseTagSetting(field.Tag.Get(tagName), ";")
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// deletedAtField returns the gorm.DeletedAt field of model, or ErrSoftDeleteUnsupported.
+func (r *Repository) deletedAtField(model any) (*schema.Field, error) {
+	s, err := r.schemaOf(model)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range s.Fields {
+		if f.FieldType == deletedAtType {
+			return f, nil
+		}
+	}
+	return nil, ErrSoftDeleteUnsupported
+}
+
+// SoftDelete marks entity as deleted by setting its gorm.DeletedAt field. Unlike Delete, it fails
+// with ErrSoftDeleteUnsupported instead of hard-deleting models without that field.
+func (r *Repository) SoftDelete(ctx context.Context, entity any) error {
+	return r.do(ctx, OpSoftDelete, entity, []any{&entity}, func(ctx context.Context) error {
+		if _, err := r.deletedAtField(entity); err != nil {
+			return err
+		}
+		return r.delete(ctx, entity)
+	})
+}
+
+// Restore clears the deletion mark of a soft-deleted entity.
+func (r *Repository) Restore(ctx context.Context, entity any) error {
+	return r.do(ctx, OpRestore, entity, []any{&entity}, func(ctx context.Context) error {
+		f, err := r.deletedAtField(entity)
+		if err != nil {
+			return err
+		}
+		return r.conn(ctx).Unscoped().Model(entity).Update(f.DBName, nil).Error
+	})
+}
+
+// ForceDelete permanently deletes entity, even if its model supports soft deletes.
+func (r *Repository) ForceDelete(ctx context.Context, entity any) error {
+	return r.do(ctx, OpForceDelete, entity, []any{&entity}, func(ctx context.Context) error {
+		return r.conn(ctx).Unscoped().Delete(entity).Error
+	})
+}
+
+// Purge permanently deletes the records of model soft-deleted more than olderThan ago and returns
+// how many were removed. It is meant for retention jobs.
+func (r *Repository) Purge(ctx context.Context, model any, olderThan time.Duration) (int64, error) {
+	return call(ctx, r, OpPurge, model, []any{&model, &olderThan}, func(ctx context.Context) (int64, error) {
+		f, err := r.deletedAtField(model)
+		if err != nil {
+			return 0, err
+		}
+		cutoff := time.Now().Add(-olderThan)
+		res := r.conn(ctx).Unscoped().
+			Where(clause.Lt{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: cutoff}).
+			Delete(model)
+		return res.RowsAffected, res.Error
+	})
+}
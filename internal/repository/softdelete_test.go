@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func seedDealers(t *testing.T, repo *Repository, names ...string) []Dealer {
+	t.Helper()
+	dealers := make([]Dealer, len(names))
+	for i, name := range names {
+		dealers[i] = Dealer{Name: name}
+		if err := repo.Create(context.Background(), &dealers[i]); err != nil {
+			t.Fatalf("failed to create dealer: %v", err)
+		}
+	}
+	return dealers
+}
+
+func TestDealerRepository_SoftDeleteAndRestore(t *testing.T) {
+	db := setupTestDB(t, &Dealer{})
+	repo := New(db)
+	ctx := context.Background()
+	dealers := seedDealers(t, repo, "North", "South", "East")
+
+	if err := repo.SoftDelete(ctx, &dealers[0]); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+
+	var active, all, trashed []Dealer
+	if err := repo.GetAll(ctx, &Dealer{}, &active); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if err := repo.GetAll(ctx, &Dealer{}, &all, WithTrashed()); err != nil {
+		t.Fatalf("GetAll WithTrashed failed: %v", err)
+	}
+	if err := repo.Find(ctx, NewSpec(&Dealer{}), &trashed, OnlyTrashed()); err != nil {
+		t.Fatalf("Find OnlyTrashed failed: %v", err)
+	}
+	if len(active) != 2 || len(all) != 3 || len(trashed) != 1 || trashed[0].Name != "North" {
+		t.Errorf("got active=%d all=%d trashed=%+v", len(active), len(all), trashed)
+	}
+
+	var got Dealer
+	if err := repo.GetByID(ctx, &Dealer{}, dealers[0].ID, &got, OnlyTrashed()); err != nil || got.Name != "North" {
+		t.Fatalf("GetByID OnlyTrashed = %+v, %v", got, err)
+	}
+
+	if err := repo.Restore(ctx, &dealers[0]); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	total, err := repo.Count(ctx, NewSpec(&Dealer{}))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 active dealers after restore, got %d", total)
+	}
+}
+
+func TestDealerRepository_ForceDelete(t *testing.T) {
+	db := setupTestDB(t, &Dealer{})
+	repo := New(db)
+	ctx := context.Background()
+	dealers := seedDealers(t, repo, "North")
+
+	if err := repo.ForceDelete(ctx, &dealers[0]); err != nil {
+		t.Fatalf("ForceDelete failed: %v", err)
+	}
+	var got Dealer
+	err := db.Unscoped().First(&got, dealers[0].ID).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound after ForceDelete, got %v", err)
+	}
+}
+
+func TestDealerRepository_Purge(t *testing.T) {
+	db := setupTestDB(t, &Dealer{})
+	repo := New(db)
+	ctx := context.Background()
+	dealers := seedDealers(t, repo, "North", "South", "East")
+
+	for _, d := range dealers[:2] {
+		if err := repo.SoftDelete(ctx, &d); err != nil {
+			t.Fatalf("SoftDelete failed: %v", err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := db.Unscoped().Model(&Dealer{}).Where("id = ?", dealers[0].ID).Update("deleted_at", old).Error; err != nil {
+		t.Fatalf("failed to age dealer: %v", err)
+	}
+
+	purged, err := repo.Purge(ctx, &Dealer{}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("expected 1 purged dealer, got %d", purged)
+	}
+	total, err := repo.Count(ctx, NewSpec(&Dealer{}), WithTrashed())
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("expected 2 dealers left, got %d", total)
+	}
+}
+
+func TestCarRepository_SoftDeleteUnsupported(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	car := Car{Brand: "Fiat"}
+	if err := repo.Create(ctx, &car); err != nil {
+		t.Fatalf("failed to create car: %v", err)
+	}
+	if err := repo.SoftDelete(ctx, &car); !errors.Is(err, ErrSoftDeleteUnsupported) {
+		t.Errorf("expected ErrSoftDeleteUnsupported, got %v", err)
+	}
+	var cars []Car
+	if err := repo.GetAll(ctx, &Car{}, &cars, OnlyTrashed()); !errors.Is(err, ErrSoftDeleteUnsupported) {
+		t.Errorf("expected ErrSoftDeleteUnsupported for OnlyTrashed, got %v", err)
+	}
+}
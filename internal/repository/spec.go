@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Spec describes which records a query targets: the model, its conditions and ordering.
+// A nil *Spec targets every record of the model the caller provides.
+type Spec struct {
+	model  any
+	conds  []condition
+	orders []string
+	limit  int
+}
+
+type condition struct {
+	query string
+	args  []any
+}
+
+// NewSpec creates a Spec for the given model.
+// model: a pointer to the model type or model instance for GORM's Model(). It may be nil
+// for the generic helpers (Paginate, ...), which fall back to the element type.
+func NewSpec(model any) *Spec {
+	return &Spec{model: model}
+}
+
+// Where adds a raw condition (e.g. "year > ?", 2018). Conditions are joined with AND.
+func (s *Spec) Where(query string, args ...any) *Spec {
+	s.conds = append(s.conds, condition{query: query, args: args})
+	return s
+}
+
+// Eq adds a field = value condition.
+// field: column name (e.g. "key" or "email")
+func (s *Spec) Eq(field string, value any) *Spec {
+	return s.Where(fmt.Sprintf("%s = ?", field), value)
+}
+
+// OrderBy adds an ORDER BY expression (e.g. "year desc").
+func (s *Spec) OrderBy(order string) *Spec {
+	s.orders = append(s.orders, order)
+	return s
+}
+
+// Limit caps the number of records returned by finds (0 means no limit).
+func (s *Spec) Limit(n int) *Spec {
+	s.limit = n
+	return s
+}
+
+// Model returns the model the Spec targets.
+func (s *Spec) Model() any {
+	if s == nil {
+		return nil
+	}
+	return s.model
+}
+
+// modelOr returns the Spec model, or fallback when the Spec has none.
+func (s *Spec) modelOr(fallback any) any {
+	if m := s.Model(); m != nil {
+		return m
+	}
+	return fallback
+}
+
+// apply adds the Spec conditions, ordering and limit to db.
+func (s *Spec) apply(db *gorm.DB) *gorm.DB {
+	db = s.applyWhere(db)
+	if s == nil {
+		return db
+	}
+	for _, o := range s.orders {
+		db = db.Order(o)
+	}
+	if s.limit > 0 {
+		db = db.Limit(s.limit)
+	}
+	return db
+}
+
+// applyWhere adds only the Spec conditions to db (ordering breaks COUNT on some dialects).
+func (s *Spec) applyWhere(db *gorm.DB) *gorm.DB {
+	if s == nil {
+		return db
+	}
+	for _, c := range s.conds {
+		db = db.Where(c.query, c.args...)
+	}
+	return db
+}
+
+// Find finds the records matching spec and scans them into out.
+func (r *Repository) Find(ctx context.Context, spec *Spec, out any, opts ...QueryOption) error {
+	return r.do(ctx, OpFind, spec.modelOr(out), []any{&spec, &out, &opts}, func(ctx context.Context) error {
+		q, err := r.query(ctx, spec.modelOr(out), opts)
+		if err != nil {
+			return err
+		}
+		return spec.apply(q).Find(out).Error
+	})
+}
+
+// Count returns the number of records matching spec.
+func (r *Repository) Count(ctx context.Context, spec *Spec, opts ...QueryOption) (int64, error) {
+	return call(ctx, r, OpCount, spec.Model(), []any{&spec, &opts}, func(ctx context.Context) (int64, error) {
+		q, err := r.countQuery(ctx, spec.Model(), opts)
+		if err != nil {
+			return 0, err
+		}
+		var total int64
+		err = spec.applyWhere(q).Count(&total).Error
+		return total, err
+	})
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// ScanHook is implemented by GORM plugins transforming the records read by queries in a query
+// callback (e.g. decryption). Iterate scans rows without running the query callbacks, so it calls
+// AfterScan on every row instead, with the row as the statement destination.
+type ScanHook interface {
+	AfterScan(db *gorm.DB)
+}
+
+// Iterate streams the records matching spec from a database cursor instead of loading them into a slice.
+// Iteration stops at the first error, which is yielded with a zero T; context cancellation is checked
+// between rows. Breaking out of the loop closes the cursor. When spec has no model, a *T is used.
+// Rows are scanned one by one, so Preload fails with ErrUnsupportedPreload: use Joins or FindInBatches.
+func Iterate[T any](ctx context.Context, r *Repository, spec *Spec, opts ...QueryOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		stopped := false
+		err := r.do(ctx, OpIterate, spec.modelOr(new(T)), []any{&spec, &opts}, func(ctx context.Context) error {
+			return iterate(ctx, r, spec, opts, func(item T, err error) bool {
+				stopped = !yield(item, err)
+				return !stopped
+			})
+		})
+		// A middleware may fail the operation after the consumer stopped
+		if err != nil && !stopped {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// iterate yields the records matching spec until the consumer stops. Errors are returned, not yielded.
+func iterate[T any](ctx context.Context, r *Repository, spec *Spec, opts []QueryOption, yield func(T, error) bool) error {
+	var o queryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.preloads) > 0 {
+		return fmt.Errorf("%w, use Joins or FindInBatches", ErrUnsupportedPreload)
+	}
+	q, err := r.query(ctx, spec.modelOr(new(T)), opts)
+	if err != nil {
+		return err
+	}
+	rows, err := spec.apply(q).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	afterScan, err := r.afterScan(ctx, spec.modelOr(new(T)), o.raw)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var item T
+		if err := q.ScanRows(rows, &item); err != nil {
+			return err
+		}
+		if err := afterScan(&item); err != nil {
+			return err
+		}
+		if !yield(item, nil) {
+			return nil
+		}
+	}
+	return rows.Err()
+}
+
+// afterScan returns the function running the ScanHook plugins on a row of model scanned into dest,
+// read with RawValues when raw is set.
+func (r *Repository) afterScan(ctx context.Context, model any, raw bool) (func(dest any) error, error) {
+	var hooks []ScanHook
+	for _, name := range slices.Sorted(maps.Keys(r.db.Config.Plugins)) {
+		if h, ok := r.db.Config.Plugins[name].(ScanHook); ok {
+			hooks = append(hooks, h)
+		}
+	}
+	if len(hooks) == 0 {
+		return func(any) error { return nil }, nil
+	}
+	db := r.db.Session(&gorm.Session{NewDB: true, Context: ctx}).Model(model)
+	if raw {
+		db = db.Set(rawKey, true)
+	}
+	if err := db.Statement.Parse(model); err != nil {
+		return nil, err
+	}
+	return func(dest any) error {
+		db.Statement.Dest = dest
+		db.Statement.ReflectValue = reflect.Indirect(reflect.ValueOf(dest))
+		for _, h := range hooks {
+			h.AfterScan(db)
+			if db.Error != nil {
+				return db.Error
+			}
+		}
+		return nil
+	}, nil
+}
+
+// FindInBatches loads the records matching spec in batches of batchSize, ordered by primary key,
+// and calls fn for each batch. Returning an error from fn, or cancelling ctx, stops the iteration.
+// Spec ordering is ignored since batches are keyed on the primary key; its limit stops the
+// iteration after that many records. It joins the transaction when r is a txRepo.
+func FindInBatches[T any](ctx context.Context, r *Repository, spec *Spec, batchSize int, fn func(batch []T) error, opts ...QueryOption) error {
+	return r.do(ctx, OpFindInBatches, spec.modelOr(new(T)), []any{&spec, &batchSize, &fn, &opts}, func(ctx context.Context) error {
+		return findInBatches(ctx, r, spec, batchSize, fn, opts)
+	})
+}
+
+// findInBatches is FindInBatches without the middleware chain.
+func findInBatches[T any](ctx context.Context, r *Repository, spec *Spec, batchSize int, fn func(batch []T) error, opts []QueryOption) error {
+	if batchSize <= 0 {
+		return ErrInvalidBatchSize
+	}
+	q, err := r.query(ctx, spec.modelOr(new(T)), opts)
+	if err != nil {
+		return err
+	}
+	q = spec.applyWhere(q)
+	if spec != nil && spec.limit > 0 {
+		q = q.Limit(spec.limit)
+	}
+	var batch []T
+	return q.FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(batch)
+	}).Error
+}
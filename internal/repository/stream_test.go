@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIterate(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	seedCars(t, repo, carPageTestData)
+
+	var brands []string
+	for car, err := range Iterate[Car](ctx, repo, NewSpec(&Car{}).Where("year >= ?", 2019).OrderBy("id")) {
+		if err != nil {
+			t.Fatalf("Iterate failed: %v", err)
+		}
+		brands = append(brands, car.Brand)
+	}
+	if len(brands) != 3 || brands[0] != "Toyota" || brands[2] != "Mazda" {
+		t.Errorf("expected Toyota, Peugeot, Mazda, got %v", brands)
+	}
+}
+
+func TestIterate_Break(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	seedCars(t, repo, carPageTestData)
+
+	seen := 0
+	for _, err := range Iterate[Car](ctx, repo, nil) {
+		if err != nil {
+			t.Fatalf("Iterate failed: %v", err)
+		}
+		seen++
+		if seen == 2 {
+			break
+		}
+	}
+	if seen != 2 {
+		t.Errorf("expected to stop after 2 cars, got %d", seen)
+	}
+
+	// The cursor must be released so the connection can be reused.
+	var cars []Car
+	if err := repo.GetAll(ctx, &Car{}, &cars); err != nil {
+		t.Fatalf("GetAll after break failed: %v", err)
+	}
+}
+
+func TestIterate_ContextCanceled(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	seedCars(t, repo, carPageTestData)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var gotErr error
+	for _, err := range Iterate[Car](ctx, repo, nil) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		cancel()
+	}
+	if !errors.Is(gotErr, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", gotErr)
+	}
+}
+
+func TestFindInBatches(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	seedCars(t, repo, carPageTestData)
+
+	var sizes []int
+	err := FindInBatches(ctx, repo, nil, 2, func(batch []Car) error {
+		sizes = append(sizes, len(batch))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FindInBatches failed: %v", err)
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[2] != 1 {
+		t.Errorf("expected batches of 2, 2, 1, got %v", sizes)
+	}
+
+	var ids []uint
+	err = FindInBatches(ctx, repo, NewSpec(&Car{}).Limit(3), 2, func(batch []Car) error {
+		for _, c := range batch {
+			ids = append(ids, c.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FindInBatches failed: %v", err)
+	}
+	if len(ids) != 3 || ids[2] != 3 {
+		t.Errorf("expected the first 3 cars, got %v", ids)
+	}
+}
+
+func TestFindInBatches_StopsOnError(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	seedCars(t, repo, carPageTestData)
+
+	errStop := errors.New("stop")
+	calls := 0
+	err := FindInBatches(ctx, repo, nil, 2, func(batch []Car) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected errStop, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+
+	if err := FindInBatches(ctx, repo, nil, 0, func([]Car) error { return nil }); !errors.Is(err, ErrInvalidBatchSize) {
+		t.Errorf("expected ErrInvalidBatchSize, got %v", err)
+	}
+}
+
+func TestFindInBatches_InTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	seedCars(t, repo, carPageTestData)
+
+	err := repo.Transaction(ctx, func(txRepo *Repository) error {
+		if err := txRepo.Create(ctx, &Car{Brand: "Fiat", Color: "Yellow", Year: 2015, Model: "Uno"}); err != nil {
+			return err
+		}
+		total := 0
+		err := FindInBatches(ctx, txRepo, NewSpec(&Car{}).Where("year < ?", 2019), 10, func(batch []Car) error {
+			total += len(batch)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if total != 3 {
+			t.Errorf("expected 3 cars older than 2019 inside the transaction, got %d", total)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+}
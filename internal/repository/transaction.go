@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// savepointSeq numbers the savepoints created by nested transactions.
+var savepointSeq atomic.Uint64
+
+// txKey is the context key holding the active transaction.
+type txKey struct{}
+
+// Propagation defines how a transaction relates to the one already active, if any.
+// The active transaction is the one the Repository is bound to (txRepo) or, otherwise,
+// the one carried by the context (see RunInTransaction).
+type Propagation int
+
+const (
+	// PropagationRequired joins the active transaction, or starts one when there is none.
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew always starts an independent transaction on a new connection, which
+	// commits or rolls back regardless of the active one.
+	PropagationRequiresNew
+	// PropagationMandatory joins the active transaction, failing with ErrNotInTransaction when there is none.
+	PropagationMandatory
+	// PropagationNever runs without a transaction, failing with ErrTransactionExists when one is active.
+	PropagationNever
+	// PropagationNested runs inside a savepoint of the active transaction, so a failure only undoes
+	// the nested work, or starts a transaction when there is none.
+	PropagationNested
+)
+
+// TxOption configures Transaction and RunInTransaction.
+type TxOption func(*txConfig)
+
+type txConfig struct {
+	propagation Propagation
+	options     sql.TxOptions
+	maxRetries  int
+	backoff     Backoff
+	onRetry     func(attempt int, err error)
+	leakTimeout time.Duration
+}
+
+// WithPropagation sets how the transaction relates to the active one.
+func WithPropagation(p Propagation) TxOption {
+	return func(c *txConfig) {
+		c.propagation = p
+	}
+}
+
+// WithIsolation sets the isolation level of the transaction (e.g. sql.LevelSerializable).
+// It only applies when a new transaction is started, not when joining or nesting.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(c *txConfig) {
+		c.options.Isolation = level
+	}
+}
+
+// ReadOnly starts the transaction in read-only mode. It only applies when a new transaction is started.
+func ReadOnly() TxOption {
+	return func(c *txConfig) {
+		c.options.ReadOnly = true
+	}
+}
+
+// WithRetry reruns the whole callback, in a new transaction, up to maxRetries times when it fails
+// with an error classified by IsRetryable, waiting backoff between attempts (default: exponential
+// from 10ms up to 1s). Once retries run out a *RetryError reporting the attempts is returned.
+// It only applies when a new transaction is started: joined or nested work is retried by its owner.
+func WithRetry(maxRetries int, backoff Backoff) TxOption {
+	return func(c *txConfig) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// OnRetry registers fn to be called before each retry with the failed attempt number and its error.
+func OnRetry(fn func(attempt int, err error)) TxOption {
+	return func(c *txConfig) {
+		c.onRetry = fn
+	}
+}
+
+// newTxConfig applies opts over the given default propagation.
+func newTxConfig(propagation Propagation, opts []TxOption) txConfig {
+	cfg := txConfig{propagation: propagation}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.backoff == nil {
+		cfg.backoff = ExponentialBackoff(10*time.Millisecond, time.Second)
+	}
+	return cfg
+}
+
+// Transaction runs the provided function inside a transaction. Commit is automatic when fn returns nil,
+// rollback if fn returns an error. The txRepo provided uses the transactional *gorm.DB.
+//
+// Called on a txRepo, or with a context carrying a transaction, Transaction nests by default
+// (PropagationNested): it creates a savepoint and, when fn returns an error or panics, rolls back
+// to it, undoing only the inner work. The outer transaction stays usable and decides whether the
+// inner work is finally committed. Use WithPropagation to change this.
+// On SQL Server savepoints map to SAVE TRANSACTION / ROLLBACK TRANSACTION <name>. Errors that doom
+// the whole transaction there (e.g. with XACT_ABORT ON, or deadlocks) cannot be undone by rolling
+// back to a savepoint: the rollback fails and the outer transaction must be rolled back as well.
+func (r *Repository) Transaction(ctx context.Context, fn func(txRepo *Repository) error, opts ...TxOption) error {
+	cfg := newTxConfig(PropagationNested, opts)
+	return r.transact(ctx, cfg, func(_ context.Context, tx *gorm.DB) error {
+		return fn(r.withDB(tx))
+	})
+}
+
+// RunInTransaction runs fn inside a transaction stored in the context passed to fn: every Repository
+// method called with that context joins it, so services don't need to thread a txRepo around.
+// By default it joins the active transaction or starts one (PropagationRequired); use
+// WithPropagation to change this. Commit and rollback work as in Transaction.
+func (r *Repository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	cfg := newTxConfig(PropagationRequired, opts)
+	return r.transact(ctx, cfg, func(txCtx context.Context, _ *gorm.DB) error {
+		return fn(txCtx)
+	})
+}
+
+// transact resolves the propagation and calls fn with the context and connection it must use.
+func (r *Repository) transact(ctx context.Context, cfg txConfig, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	active := r.activeTx(ctx)
+	switch cfg.propagation {
+	case PropagationMandatory:
+		if active == nil {
+			return ErrNotInTransaction
+		}
+		return fn(withTx(ctx, active), active)
+	case PropagationNever:
+		if active != nil {
+			return ErrTransactionExists
+		}
+		return fn(ctx, r.db)
+	case PropagationRequired:
+		if active != nil {
+			return fn(withTx(ctx, active), active)
+		}
+	case PropagationNested:
+		if active != nil {
+			return nestedTransaction(ctx, active, fn)
+		}
+	}
+	return cfg.retry(ctx, func() error {
+		var hooks *txHooks
+		err := r.root.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			hooks = watchCommit(tx)
+			defer unwatch(tx)
+			return fn(withTx(ctx, tx), tx)
+		}, &cfg.options)
+		if err == nil {
+			hooks.run()
+		}
+		return err
+	})
+}
+
+// nestedTransaction runs fn between a savepoint of active and, on failure, a rollback to it.
+func nestedTransaction(ctx context.Context, active *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) (err error) {
+	name := fmt.Sprintf("gormr_sp_%d", savepointSeq.Add(1))
+	tx := active.WithContext(ctx)
+	if err := tx.SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	panicked := true
+	defer func() {
+		if !panicked && err == nil {
+			return
+		}
+		if rbErr := tx.RollbackTo(name).Error; rbErr != nil && err != nil {
+			err = fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+	}()
+
+	err = fn(withTx(ctx, active), active)
+	panicked = false
+	return err
+}
+
+// Savepoint creates a savepoint named name in the active transaction, so the caller can later
+// undo the work done after it with RollbackTo. It returns ErrNotInTransaction outside a transaction.
+func (r *Repository) Savepoint(ctx context.Context, name string) error {
+	tx := r.activeTx(ctx)
+	if tx == nil {
+		return ErrNotInTransaction
+	}
+	return tx.WithContext(ctx).SavePoint(name).Error
+}
+
+// RollbackTo undoes the work done after the savepoint named name. It returns ErrNotInTransaction
+// outside a transaction.
+func (r *Repository) RollbackTo(ctx context.Context, name string) error {
+	tx := r.activeTx(ctx)
+	if tx == nil {
+		return ErrNotInTransaction
+	}
+	return tx.WithContext(ctx).RollbackTo(name).Error
+}
+
+// ManualTx returns a started transaction (*gorm.DB) so the caller can control Commit/Rollback.
+// Caller must call tx.Commit() or tx.Rollback().
+//
+// Deprecated: use Begin, whose Tx keeps the Repository API, tolerates Rollback after Commit and can
+// report leaked transactions.
+func (r *Repository) ManualTx(ctx context.Context) (*gorm.DB, error) {
+	tx := r.db.WithContext(ctx).Begin()
+	return tx, tx.Error
+}
+
+// conn returns the connection to run a query with: the active transaction if any, the repository DB otherwise.
+func (r *Repository) conn(ctx context.Context) *gorm.DB {
+	if tx := r.activeTx(ctx); tx != nil {
+		return tx.WithContext(ctx)
+	}
+	return r.db.WithContext(ctx)
+}
+
+// activeTx returns the transaction the repository is bound to or, failing that, the one carried
+// by ctx. It returns nil when there is none.
+func (r *Repository) activeTx(ctx context.Context) *gorm.DB {
+	if isTx(r.db) {
+		return r.db
+	}
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return nil
+}
+
+// isTx reports whether db is bound to a transaction.
+func isTx(db *gorm.DB) bool {
+	committer, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
+
+// withTx returns a copy of ctx carrying tx as the active transaction.
+func withTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// withDB returns a copy of the repository bound to db.
+func (r *Repository) withDB(db *gorm.DB) *Repository {
+	clone := *r
+	clone.db = db
+	return &clone
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func brandsOf(t *testing.T, repo *Repository) map[string]bool {
+	t.Helper()
+	var cars []Car
+	if err := repo.GetAll(context.Background(), &Car{}, &cars); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	brands := map[string]bool{}
+	for _, c := range cars {
+		brands[c.Brand] = true
+	}
+	return brands
+}
+
+func TestCarRepository_NestedTransactionPartialRollback(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	errInner := errors.New("inner failure")
+
+	err := repo.Transaction(ctx, func(txRepo *Repository) error {
+		if err := txRepo.Create(ctx, &Car{Brand: "Toyota"}); err != nil {
+			return err
+		}
+		err := txRepo.Transaction(ctx, func(inner *Repository) error {
+			if err := inner.Create(ctx, &Car{Brand: "Ford"}); err != nil {
+				return err
+			}
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			t.Errorf("expected inner error, got %v", err)
+		}
+		return txRepo.Create(ctx, &Car{Brand: "Fiat"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	brands := brandsOf(t, repo)
+	if !brands["Toyota"] || !brands["Fiat"] || brands["Ford"] {
+		t.Errorf("expected only the inner work to be rolled back, got %v", brands)
+	}
+}
+
+func TestCarRepository_NestedTransactionPanic(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	err := repo.Transaction(ctx, func(txRepo *Repository) error {
+		func() {
+			defer func() { _ = recover() }()
+			_ = txRepo.Transaction(ctx, func(inner *Repository) error {
+				if err := inner.Create(ctx, &Car{Brand: "Ford"}); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		}()
+		return txRepo.Create(ctx, &Car{Brand: "Fiat"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	brands := brandsOf(t, repo)
+	if !brands["Fiat"] || brands["Ford"] {
+		t.Errorf("expected the panicking inner work to be rolled back, got %v", brands)
+	}
+}
+
+func TestCarRepository_OuterRollbackUndoesNested(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	errOuter := errors.New("outer failure")
+
+	err := repo.Transaction(ctx, func(txRepo *Repository) error {
+		if err := txRepo.Transaction(ctx, func(inner *Repository) error {
+			return inner.Create(ctx, &Car{Brand: "Ford"})
+		}); err != nil {
+			return err
+		}
+		return errOuter
+	})
+	if !errors.Is(err, errOuter) {
+		t.Fatalf("expected outer error, got %v", err)
+	}
+	if brands := brandsOf(t, repo); len(brands) != 0 {
+		t.Errorf("expected no cars after outer rollback, got %v", brands)
+	}
+}
+
+func TestCarRepository_Savepoint(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	if err := repo.Savepoint(ctx, "sp1"); !errors.Is(err, ErrNotInTransaction) {
+		t.Errorf("expected ErrNotInTransaction, got %v", err)
+	}
+
+	err := repo.Transaction(ctx, func(txRepo *Repository) error {
+		if err := txRepo.Create(ctx, &Car{Brand: "Toyota"}); err != nil {
+			return err
+		}
+		if err := txRepo.Savepoint(ctx, "before_ford"); err != nil {
+			return err
+		}
+		if err := txRepo.Create(ctx, &Car{Brand: "Ford"}); err != nil {
+			return err
+		}
+		return txRepo.RollbackTo(ctx, "before_ford")
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	brands := brandsOf(t, repo)
+	if !brands["Toyota"] || brands["Ford"] {
+		t.Errorf("expected only Toyota, got %v", brands)
+	}
+}
+
+func TestCarRepository_RunInTransactionJoinsContext(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	errFail := errors.New("fail")
+
+	err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, &Car{Brand: "Toyota"}); err != nil {
+			return err
+		}
+		var cars []Car
+		if err := repo.GetAll(ctx, &Car{}, &cars); err != nil {
+			return err
+		}
+		if len(cars) != 1 {
+			t.Errorf("expected to read the uncommitted car through ctx, got %d", len(cars))
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("expected fail error, got %v", err)
+	}
+	if brands := brandsOf(t, repo); len(brands) != 0 {
+		t.Errorf("expected the ctx transaction to be rolled back, got %v", brands)
+	}
+}
+
+func TestCarRepository_AfterCommit(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	errFail := errors.New("fail")
+
+	var hooks []string
+	repo.AfterCommit(ctx, func() { hooks = append(hooks, "now") })
+	_ = repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		repo.AfterCommit(ctx, func() { hooks = append(hooks, "rolled back") })
+		return errFail
+	})
+	err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		repo.AfterCommit(ctx, func() { hooks = append(hooks, "outer") })
+		if !repo.InTransaction(ctx) {
+			t.Errorf("expected InTransaction to report the ctx transaction")
+		}
+		return repo.Transaction(ctx, func(txRepo *Repository) error {
+			txRepo.AfterCommit(ctx, func() { hooks = append(hooks, "nested") })
+			if len(hooks) != 1 {
+				t.Errorf("expected hooks to wait for the commit, got %v", hooks)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction failed: %v", err)
+	}
+	if strings.Join(hooks, ",") != "now,outer,nested" {
+		t.Errorf("unexpected hooks: %v", hooks)
+	}
+
+	tx, err := repo.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	hooks = nil
+	repo.AfterCommit(tx.Context(ctx), func() { hooks = append(hooks, "begin") })
+	if err := tx.Commit(); err != nil || len(hooks) != 1 {
+		t.Errorf("expected the hook to run on Commit, got %v (%v)", hooks, err)
+	}
+	if repo.InTransaction(ctx) {
+		t.Errorf("expected no transaction outside RunInTransaction")
+	}
+}
+
+func TestCarRepository_Propagation(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	err := repo.RunInTransaction(ctx, func(context.Context) error { return nil }, WithPropagation(PropagationMandatory))
+	if !errors.Is(err, ErrNotInTransaction) {
+		t.Errorf("expected ErrNotInTransaction for Mandatory, got %v", err)
+	}
+
+	err = repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.RunInTransaction(ctx, func(context.Context) error { return nil }, WithPropagation(PropagationNever)); !errors.Is(err, ErrTransactionExists) {
+			t.Errorf("expected ErrTransactionExists for Never, got %v", err)
+		}
+		return repo.RunInTransaction(ctx, func(ctx context.Context) error {
+			return repo.Create(ctx, &Car{Brand: "Ford"})
+		}, WithPropagation(PropagationMandatory))
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction failed: %v", err)
+	}
+
+	err = repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, &Car{Brand: "Toyota"}); err != nil {
+			return err
+		}
+		_ = repo.RunInTransaction(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, &Car{Brand: "Fiat"}); err != nil {
+				return err
+			}
+			return errors.New("nested failure")
+		}, WithPropagation(PropagationNested))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction failed: %v", err)
+	}
+
+	brands := brandsOf(t, repo)
+	if !brands["Ford"] || !brands["Toyota"] || brands["Fiat"] {
+		t.Errorf("expected Ford and Toyota only, got %v", brands)
+	}
+}
+
+func TestCarRepository_PropagationRequiresNew(t *testing.T) {
+	db := setupFileTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	errOuter := errors.New("outer failure")
+
+	err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+			return repo.Create(ctx, &Car{Brand: "Audit"})
+		}, WithPropagation(PropagationRequiresNew)); err != nil {
+			return err
+		}
+		if err := repo.Create(ctx, &Car{Brand: "Toyota"}); err != nil {
+			return err
+		}
+		return errOuter
+	})
+	if !errors.Is(err, errOuter) {
+		t.Fatalf("expected outer error, got %v", err)
+	}
+
+	brands := brandsOf(t, repo)
+	if !brands["Audit"] || brands["Toyota"] {
+		t.Errorf("expected the independent transaction to survive the outer rollback, got %v", brands)
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Tx is a manually controlled transaction started by Begin. It embeds a Repository bound to the
+// transaction, so every Repository method can be called on it, and must be finished with Commit or
+// Rollback. Rollback after Commit is a no-op, so `defer tx.Rollback()` is always safe.
+type Tx struct {
+	*Repository
+
+	mu   sync.Mutex
+	done bool
+	// hooks run after commit, registered with AfterCommit on the Tx or the Repository
+	hooks         *txHooks
+	afterRollback []func()
+	leakTimer     *time.Timer
+}
+
+// WithLeakTimeout makes Begin log, through the GORM logger, the creation stack of transactions still
+// unfinished after timeout, to track down leaked transactions holding connections.
+func WithLeakTimeout(timeout time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.leakTimeout = timeout
+	}
+}
+
+// Begin starts a transaction the caller controls with Commit and Rollback. WithIsolation, ReadOnly
+// and WithLeakTimeout apply; propagation and retry options are ignored.
+func (r *Repository) Begin(ctx context.Context, opts ...TxOption) (*Tx, error) {
+	cfg := newTxConfig(PropagationRequiresNew, opts)
+	db := r.root.WithContext(ctx).Begin(&cfg.options)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+
+	tx := &Tx{Repository: r.withDB(db), hooks: watchCommit(db)}
+	if cfg.leakTimeout > 0 {
+		stack := debug.Stack()
+		tx.leakTimer = time.AfterFunc(cfg.leakTimeout, func() {
+			db.Logger.Warn(ctx, "gormr: transaction not finished after %s, created at:\n%s", cfg.leakTimeout, stack)
+		})
+	}
+	return tx, nil
+}
+
+// Context returns a copy of ctx carrying the transaction, so Repository methods called with it
+// join the transaction as in RunInTransaction.
+func (t *Tx) Context(ctx context.Context) context.Context {
+	return withTx(ctx, t.db)
+}
+
+// Commit commits the transaction and runs the AfterCommit hooks. If the commit fails the
+// AfterRollback hooks run instead. It returns sql.ErrTxDone if the transaction is already finished.
+func (t *Tx) Commit() error {
+	if !t.finish() {
+		return sql.ErrTxDone
+	}
+	if err := t.db.Commit().Error; err != nil {
+		t.runHooks(false)
+		return err
+	}
+	t.runHooks(true)
+	return nil
+}
+
+// Rollback rolls the transaction back and runs the AfterRollback hooks. It is a no-op returning
+// nil once the transaction is finished, so it can be deferred right after Begin.
+func (t *Tx) Rollback() error {
+	if !t.finish() {
+		return nil
+	}
+	err := t.db.Rollback().Error
+	t.runHooks(false)
+	return err
+}
+
+// AfterCommit registers fn to run once the transaction is committed.
+func (t *Tx) AfterCommit(fn func()) {
+	t.hooks.add(fn)
+}
+
+// AfterRollback registers fn to run once the transaction is rolled back.
+func (t *Tx) AfterRollback(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.afterRollback = append(t.afterRollback, fn)
+}
+
+// finish marks the transaction as finished, reporting false if it already was.
+func (t *Tx) finish() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return false
+	}
+	t.done = true
+	unwatch(t.db)
+	if t.leakTimer != nil {
+		t.leakTimer.Stop()
+	}
+	return true
+}
+
+// runHooks calls the AfterCommit or AfterRollback hooks in registration order.
+func (t *Tx) runHooks(committed bool) {
+	if committed {
+		t.hooks.run()
+		return
+	}
+	t.mu.Lock()
+	hooks := t.afterRollback
+	t.mu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// captureLogger records the warnings logged by GORM.
+type captureLogger struct {
+	logger.Interface
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (l *captureLogger) Warn(_ context.Context, msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, fmt.Sprintf(msg, args...))
+}
+
+func (l *captureLogger) messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.warnings...)
+}
+
+func TestCarRepository_BeginCommit(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	tx, err := repo.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var hooks []string
+	tx.AfterCommit(func() { hooks = append(hooks, "commit") })
+	tx.AfterRollback(func() { hooks = append(hooks, "rollback") })
+
+	if err := tx.Create(ctx, &Car{Brand: "Chevrolet"}); err != nil {
+		t.Fatalf("Create in tx failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("expected Rollback after Commit to be a no-op, got %v", err)
+	}
+	if err := tx.Commit(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("expected sql.ErrTxDone on second Commit, got %v", err)
+	}
+	if len(hooks) != 1 || hooks[0] != "commit" {
+		t.Errorf("expected only the commit hook, got %v", hooks)
+	}
+	if brands := brandsOf(t, repo); !brands["Chevrolet"] {
+		t.Errorf("expected Chevrolet to be committed, got %v", brands)
+	}
+}
+
+func TestCarRepository_BeginRollback(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	tx, err := repo.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	rolledBack := false
+	tx.AfterRollback(func() { rolledBack = true })
+
+	// Repository methods called with the Tx context join the transaction.
+	if err := repo.Create(tx.Context(ctx), &Car{Brand: "Fiat"}); err != nil {
+		t.Fatalf("Create with tx context failed: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if !rolledBack {
+		t.Error("expected the rollback hook to run")
+	}
+	if brands := brandsOf(t, repo); len(brands) != 0 {
+		t.Errorf("expected no cars after rollback, got %v", brands)
+	}
+}
+
+func TestCarRepository_BeginLeakDetection(t *testing.T) {
+	log := &captureLogger{Interface: logger.Discard}
+	db := setupTestDB(t).Session(&gorm.Session{Logger: log})
+	repo := New(db)
+	ctx := context.Background()
+
+	finished, err := repo.Begin(ctx, WithLeakTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := finished.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	leaked, err := repo.Begin(ctx, WithLeakTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer func() { _ = leaked.Rollback() }()
+
+	deadline := time.Now().Add(time.Second)
+	for len(log.messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	msgs := log.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected exactly one leak warning, got %v", msgs)
+	}
+	if !strings.Contains(msgs[0], "TestCarRepository_BeginLeakDetection") {
+		t.Errorf("expected the warning to include the creation stack, got %q", msgs[0])
+	}
+}
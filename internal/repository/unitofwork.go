@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// UnitOfWork tracks the entities created, changed and removed while handling a request and writes
+// them all in a single transaction on Commit. Inserts are ordered so that referenced records
+// (belongs-to) are written before the records referencing them, deletes the other way around;
+// the order is derived from the GORM schema relations. Entities must be pointers.
+type UnitOfWork struct {
+	repo *Repository
+
+	mu      sync.Mutex
+	news    []any
+	dirty   []any
+	removed []any
+}
+
+// NewUnitOfWork creates an empty UnitOfWork writing through the repository.
+func (r *Repository) NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{repo: r}
+}
+
+// RegisterNew tracks entities to be inserted.
+func (u *UnitOfWork) RegisterNew(entities ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, e := range entities {
+		if !slices.Contains(u.news, e) {
+			u.news = append(u.news, e)
+		}
+	}
+}
+
+// RegisterDirty tracks entities to be updated. Entities already registered as new are
+// inserted with their latest values instead.
+func (u *UnitOfWork) RegisterDirty(entities ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, e := range entities {
+		if !slices.Contains(u.news, e) && !slices.Contains(u.dirty, e) {
+			u.dirty = append(u.dirty, e)
+		}
+	}
+}
+
+// RegisterRemoved tracks entities to be deleted. Removing an entity registered as new
+// simply forgets it, since it was never written.
+func (u *UnitOfWork) RegisterRemoved(entities ...any) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, e := range entities {
+		if slices.Contains(u.news, e) {
+			u.news = slices.DeleteFunc(u.news, func(x any) bool { return x == e })
+			continue
+		}
+		u.dirty = slices.DeleteFunc(u.dirty, func(x any) bool { return x == e })
+		if !slices.Contains(u.removed, e) {
+			u.removed = append(u.removed, e)
+		}
+	}
+}
+
+// Clear forgets every tracked entity.
+func (u *UnitOfWork) Clear() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.news, u.dirty, u.removed = nil, nil, nil
+}
+
+// Commit writes the tracked entities in one transaction (joining the active one, see
+// RunInTransaction): inserts first, then updates, then deletes. The UnitOfWork is cleared when
+// the transaction succeeds and left untouched otherwise, so Commit can be retried.
+func (u *UnitOfWork) Commit(ctx context.Context, opts ...TxOption) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	inserts, err := u.repo.sortByDependencies(u.news)
+	if err != nil {
+		return err
+	}
+	deletes, err := u.repo.sortByDependencies(u.removed)
+	if err != nil {
+		return err
+	}
+
+	err = u.repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		for _, e := range inserts {
+			if err := u.repo.Create(ctx, e); err != nil {
+				return err
+			}
+		}
+		for _, e := range u.dirty {
+			if err := u.repo.Update(ctx, e); err != nil {
+				return err
+			}
+		}
+		for i := len(deletes) - 1; i >= 0; i-- {
+			if err := u.repo.Delete(ctx, deletes[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	u.news, u.dirty, u.removed = nil, nil, nil
+	return nil
+}
+
+// sortByDependencies orders entities by table so that every table comes after the tables it
+// references. Tables involved in a reference cycle keep their registration order.
+func (r *Repository) sortByDependencies(entities []any) ([]any, error) {
+	var tables []string
+	groups := map[string][]any{}
+	schemas := map[string]*schema.Schema{}
+	for _, e := range entities {
+		s, err := r.schemaOf(e)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := groups[s.Table]; !ok {
+			tables = append(tables, s.Table)
+			schemas[s.Table] = s
+		}
+		groups[s.Table] = append(groups[s.Table], e)
+	}
+
+	deps := map[string]map[string]bool{}
+	addDep := func(table, dependsOn string) {
+		if table == dependsOn || schemas[dependsOn] == nil {
+			return
+		}
+		if deps[table] == nil {
+			deps[table] = map[string]bool{}
+		}
+		deps[table][dependsOn] = true
+	}
+	for table, s := range schemas {
+		for _, rel := range s.Relationships.Relations {
+			switch rel.Type {
+			case schema.BelongsTo:
+				addDep(table, rel.FieldSchema.Table)
+			case schema.HasOne, schema.HasMany:
+				addDep(rel.FieldSchema.Table, table)
+			}
+		}
+	}
+
+	sorted := make([]any, 0, len(entities))
+	placed := map[string]bool{}
+	for len(placed) < len(tables) {
+		progressed := false
+		for _, table := range tables {
+			if placed[table] || !allPlaced(deps[table], placed) {
+				continue
+			}
+			placed[table] = true
+			sorted = append(sorted, groups[table]...)
+			progressed = true
+		}
+		if !progressed {
+			for _, table := range tables {
+				if !placed[table] {
+					placed[table] = true
+					sorted = append(sorted, groups[table]...)
+				}
+			}
+		}
+	}
+	return sorted, nil
+}
+
+// allPlaced reports whether every table in deps is placed.
+func allPlaced(deps map[string]bool, placed map[string]bool) bool {
+	for table := range deps {
+		if !placed[table] {
+			return false
+		}
+	}
+	return true
+}
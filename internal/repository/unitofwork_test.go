@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"testing"
+)
+
+func setupOrdersDB(t *testing.T) *Repository {
+	t.Helper()
+	db := setupTestDB(t, &Customer{}, &Order{}, &OrderLine{})
+	if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+		t.Fatalf("failed to enable foreign keys: %v", err)
+	}
+	return New(db)
+}
+
+func TestUnitOfWork_CommitOrdersByDependencies(t *testing.T) {
+	repo := setupOrdersDB(t)
+	ctx := context.Background()
+
+	customer := &Customer{ID: 1, Name: "Ada"}
+	order := &Order{ID: 10, CustomerID: 1, Total: 30}
+	line := &OrderLine{ID: 100, OrderID: 10, Product: "Keyboard"}
+
+	uow := repo.NewUnitOfWork()
+	uow.RegisterNew(line, order, customer)
+	if err := uow.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var lines []OrderLine
+	if err := repo.GetAll(ctx, &OrderLine{}, &lines); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 order line, got %d", len(lines))
+	}
+
+	order.Total = 45
+	uow.RegisterDirty(order)
+	uow.RegisterRemoved(customer, line, order)
+	if err := uow.Commit(ctx); err != nil {
+		t.Fatalf("Commit of removals failed: %v", err)
+	}
+	for _, model := range []any{&Customer{}, &Order{}, &OrderLine{}} {
+		total, err := repo.Count(ctx, NewSpec(model))
+		if err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		if total != 0 {
+			t.Errorf("expected %T rows to be deleted, got %d", model, total)
+		}
+	}
+}
+
+func TestUnitOfWork_CommitIsAtomic(t *testing.T) {
+	repo := setupOrdersDB(t)
+	ctx := context.Background()
+
+	uow := repo.NewUnitOfWork()
+	uow.RegisterNew(&Customer{ID: 1, Name: "Ada"}, &Order{ID: 10, CustomerID: 99})
+	if err := uow.Commit(ctx); err == nil {
+		t.Fatal("expected a foreign key violation")
+	}
+
+	total, err := repo.Count(ctx, NewSpec(&Customer{}))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 0 {
+		t.Errorf("expected the customer insert to be rolled back, got %d", total)
+	}
+}
+
+func TestUnitOfWork_RemovingNewEntity(t *testing.T) {
+	repo := setupOrdersDB(t)
+	ctx := context.Background()
+
+	customer := &Customer{Name: "Grace"}
+	uow := repo.NewUnitOfWork()
+	uow.RegisterNew(customer)
+	uow.RegisterDirty(customer)
+	uow.RegisterRemoved(customer)
+	if err := uow.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	total, err := repo.Count(ctx, NewSpec(&Customer{}))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if total != 0 {
+		t.Errorf("expected nothing to be written, got %d customers", total)
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"maps"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateFields updates only the given fields of entity, including zero values, leaving the other
+// columns untouched. fields are struct field or column names (e.g. "Color" or "color").
+// The version of versioned entities is checked and incremented as in Update.
+func (r *Repository) UpdateFields(ctx context.Context, entity any, fields ...string) error {
+	return r.do(ctx, OpUpdateFields, entity, []any{&entity, &fields}, func(ctx context.Context) error {
+		return r.updateFields(ctx, entity, fields)
+	})
+}
+
+// updateFields is UpdateFields without the middleware chain.
+func (r *Repository) updateFields(ctx context.Context, entity any, fields []string) error {
+	if len(fields) == 0 {
+		return ErrNoChanges
+	}
+	vf, err := r.versionField(entity)
+	if err != nil {
+		return err
+	}
+	if vf == nil {
+		return r.conn(ctx).Model(entity).Select(fields).Updates(entity).Error
+	}
+	fields = append(fields[:len(fields):len(fields)], vf.Name)
+	return r.updateVersioned(ctx, entity, vf, func(q *gorm.DB, _ int64) *gorm.DB {
+		return q.Select(fields).Updates(entity)
+	})
+}
+
+// UpdateMap applies changes (column -> value) to entity, which must have its primary key set.
+// The version of versioned entities is checked and incremented as in Update.
+func (r *Repository) UpdateMap(ctx context.Context, entity any, changes map[string]any) error {
+	return r.do(ctx, OpUpdateMap, entity, []any{&entity, &changes}, func(ctx context.Context) error {
+		return r.updateMap(ctx, entity, changes)
+	})
+}
+
+// updateMap is UpdateMap without the middleware chain.
+func (r *Repository) updateMap(ctx context.Context, entity any, changes map[string]any) error {
+	if len(changes) == 0 {
+		return ErrNoChanges
+	}
+	vf, err := r.versionField(entity)
+	if err != nil {
+		return err
+	}
+	if vf == nil {
+		return r.conn(ctx).Model(entity).Updates(changes).Error
+	}
+	return r.updateVersioned(ctx, entity, vf, func(q *gorm.DB, next int64) *gorm.DB {
+		versioned := maps.Clone(changes)
+		versioned[vf.DBName] = next
+		return q.Updates(versioned)
+	})
+}
+
+// UpdateWhere applies changes (column -> value) to every record matching spec and returns the
+// number of affected rows. A spec without conditions is rejected with gorm.ErrMissingWhereClause.
+// The version of versioned models is incremented so in-memory copies become stale.
+func (r *Repository) UpdateWhere(ctx context.Context, spec *Spec, changes map[string]any) (int64, error) {
+	return call(ctx, r, OpUpdateWhere, spec.Model(), []any{&spec, &changes}, func(ctx context.Context) (int64, error) {
+		return r.updateWhere(ctx, spec, changes)
+	})
+}
+
+// updateWhere is UpdateWhere without the middleware chain.
+func (r *Repository) updateWhere(ctx context.Context, spec *Spec, changes map[string]any) (int64, error) {
+	if len(changes) == 0 {
+		return 0, ErrNoChanges
+	}
+	vf, err := r.versionField(spec.Model())
+	if err != nil {
+		return 0, err
+	}
+	if vf != nil {
+		changes = maps.Clone(changes)
+		changes[vf.DBName] = gorm.Expr("? + 1", clause.Column{Name: vf.DBName})
+	}
+	res := spec.applyWhere(r.conn(ctx).Model(spec.Model())).Updates(changes)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCarRepository_UpdateFields(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	car := Car{Brand: "Toyota", Color: "Red", Year: 2020, Model: "Corolla"}
+	if err := repo.Create(ctx, &car); err != nil {
+		t.Fatalf("failed to create car: %v", err)
+	}
+
+	// A concurrent writer changes the model; a partial update must not clobber it.
+	if err := db.Model(&Car{}).Where("id = ?", car.ID).Update("model", "Yaris").Error; err != nil {
+		t.Fatalf("failed to update model: %v", err)
+	}
+
+	car.Color = "Black"
+	car.Year = 0
+	if err := repo.UpdateFields(ctx, &car, "Color", "year"); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+
+	var got Car
+	if err := repo.GetByID(ctx, &Car{}, car.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Color != "Black" || got.Year != 0 || got.Model != "Yaris" {
+		t.Errorf("expected color and year updated and model kept, got %+v", got)
+	}
+
+	if err := repo.UpdateFields(ctx, &car); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("expected ErrNoChanges, got %v", err)
+	}
+}
+
+func TestCarRepository_UpdateMap(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+
+	car := Car{Brand: "Ford", Color: "Blue", Year: 2018, Model: "Focus"}
+	if err := repo.Create(ctx, &car); err != nil {
+		t.Fatalf("failed to create car: %v", err)
+	}
+
+	if err := repo.UpdateMap(ctx, &Car{ID: car.ID}, map[string]any{"color": "Green", "year": 2019}); err != nil {
+		t.Fatalf("UpdateMap failed: %v", err)
+	}
+
+	var got Car
+	if err := repo.GetByID(ctx, &Car{}, car.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Color != "Green" || got.Year != 2019 || got.Brand != "Ford" {
+		t.Errorf("expected color and year patched, got %+v", got)
+	}
+}
+
+func TestCarRepository_UpdateWhere(t *testing.T) {
+	db := setupTestDB(t)
+	repo := New(db)
+	ctx := context.Background()
+	seedCars(t, repo, carPageTestData)
+
+	affected, err := repo.UpdateWhere(ctx, NewSpec(&Car{}).Eq("year", 2019), map[string]any{"color": "Silver"})
+	if err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if affected != 2 {
+		t.Errorf("expected 2 affected rows, got %d", affected)
+	}
+
+	silver, err := repo.Count(ctx, NewSpec(&Car{}).Eq("color", "Silver"))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if silver != 2 {
+		t.Errorf("expected 2 silver cars, got %d", silver)
+	}
+
+	if _, err := repo.UpdateWhere(ctx, NewSpec(&Car{}), map[string]any{"color": "Pink"}); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("expected ErrMissingWhereClause for a spec without conditions, got %v", err)
+	}
+}
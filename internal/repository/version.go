@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Versioned is implemented by models that opt into optimistic locking without a struct tag.
+// VersionField returns the name of the integer struct field holding the version.
+type Versioned interface {
+	VersionField() string
+}
+
+// versionField returns the version field of entity, or nil when the model does not opt into
+// optimistic locking with a `gormr:"version"` tag or the Versioned interface.
+func (r *Repository) versionField(entity any) (*schema.Field, error) {
+	if entity == nil || reflect.Indirect(reflect.ValueOf(entity)).Kind() != reflect.Struct {
+		return nil, nil
+	}
+	s, err := r.schemaOf(entity)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := entity.(Versioned); ok {
+		f := s.LookUpField(v.VersionField())
+		if f == nil {
+			return nil, fmt.Errorf("gormr: unknown version field %q for %s", v.VersionField(), s.Name)
+		}
+		return f, nil
+	}
+	for _, f := range s.Fields {
+		if _, ok := tagSettings(f)["VERSION"]; ok {
+			return f, nil
+		}
+	}
+	return nil, nil
+}
+
+// updateVersioned increments the version of entity and runs update restricted to the version it
+// was loaded with. When no row matches, the version is restored and ErrStaleObject returned.
+func (r *Repository) updateVersioned(ctx context.Context, entity any, f *schema.Field, update func(q *gorm.DB, next int64) *gorm.DB) error {
+	if err := r.requireKey(ctx, entity); err != nil {
+		return err
+	}
+	current, err := versionOf(ctx, f, entity)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(entity)
+	if err := f.Set(ctx, rv, current+1); err != nil {
+		return err
+	}
+	res := update(r.conn(ctx).Model(entity).Where(versionEq(f, current)), current+1)
+	if res.Error == nil && res.RowsAffected == 0 {
+		res.Error = ErrStaleObject
+	}
+	if res.Error != nil {
+		_ = f.Set(ctx, rv, current)
+	}
+	return res.Error
+}
+
+// deleteVersioned deletes entity only if it still has the version it was loaded with.
+func (r *Repository) deleteVersioned(ctx context.Context, entity any, f *schema.Field) error {
+	if err := r.requireKey(ctx, entity); err != nil {
+		return err
+	}
+	current, err := versionOf(ctx, f, entity)
+	if err != nil {
+		return err
+	}
+	res := r.conn(ctx).Where(versionEq(f, current)).Delete(entity)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrStaleObject
+	}
+	return res.Error
+}
+
+// requireKey returns gorm.ErrMissingWhereClause when entity has no primary key value: the version
+// condition alone would otherwise pass GORM's guard and match every row at that version.
+func (r *Repository) requireKey(ctx context.Context, entity any) error {
+	s, err := r.schemaOf(entity)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(entity)
+	for _, pk := range s.PrimaryFields {
+		if _, zero := pk.ValueOf(ctx, rv); !zero {
+			return nil
+		}
+	}
+	return gorm.ErrMissingWhereClause
+}
+
+// versionOf reads the version of entity as an int64.
+func versionOf(ctx context.Context, f *schema.Field, entity any) (int64, error) {
+	v, _ := f.ValueOf(ctx, reflect.ValueOf(entity))
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return rv.Int(), nil
+	case rv.CanUint():
+		return int64(rv.Uint()), nil
+	default:
+		return 0, fmt.Errorf("gormr: version field %s must be an integer", f.Name)
+	}
+}
+
+// versionEq is the WHERE condition matching version v.
+func versionEq(f *schema.Field, v int64) clause.Eq {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: v}
+}
+
+// isNew reports whether entity has a zero primary key, i.e. Save would insert it.
+func (r *Repository) isNew(ctx context.Context, entity any) (bool, error) {
+	s, err := r.schemaOf(entity)
+	if err != nil {
+		return false, err
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return false, nil
+	}
+	_, zero := s.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(entity))
+	return zero, nil
+}
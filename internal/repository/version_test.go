@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestPartRepository_UpdateVersioned(t *testing.T) {
+	db := setupTestDB(t, &Part{})
+	repo := New(db)
+	ctx := context.Background()
+
+	part := Part{Name: "Bolt", Stock: 10}
+	if err := repo.Create(ctx, &part); err != nil {
+		t.Fatalf("failed to create part: %v", err)
+	}
+
+	var first, second Part
+	if err := repo.GetByID(ctx, &Part{}, part.ID, &first); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if err := repo.GetByID(ctx, &Part{}, part.ID, &second); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+
+	first.Stock = 8
+	if err := repo.Update(ctx, &first); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if first.Version != 1 {
+		t.Errorf("expected version 1 after update, got %d", first.Version)
+	}
+
+	second.Stock = 12
+	if err := repo.Update(ctx, &second); !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("expected ErrStaleObject, got %v", err)
+	}
+	if second.Version != 0 {
+		t.Errorf("expected version to be restored to 0, got %d", second.Version)
+	}
+
+	var got Part
+	if err := repo.GetByID(ctx, &Part{}, part.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Stock != 8 || got.Version != 1 {
+		t.Errorf("expected stock 8 at version 1, got %+v", got)
+	}
+}
+
+func TestPartRepository_PartialUpdatesVersioned(t *testing.T) {
+	db := setupTestDB(t, &Part{})
+	repo := New(db)
+	ctx := context.Background()
+
+	part := Part{Name: "Nut", Stock: 5}
+	if err := repo.Create(ctx, &part); err != nil {
+		t.Fatalf("failed to create part: %v", err)
+	}
+	stale := part
+
+	part.Stock = 4
+	if err := repo.UpdateFields(ctx, &part, "Stock"); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	if err := repo.UpdateMap(ctx, &part, map[string]any{"name": "Hex nut"}); err != nil {
+		t.Fatalf("UpdateMap failed: %v", err)
+	}
+	if part.Version != 2 {
+		t.Errorf("expected version 2 after two partial updates, got %d", part.Version)
+	}
+	if err := repo.UpdateMap(ctx, &stale, map[string]any{"stock": 0}); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("expected ErrStaleObject, got %v", err)
+	}
+
+	affected, err := repo.UpdateWhere(ctx, NewSpec(&Part{}).Eq("id", part.ID), map[string]any{"stock": 3})
+	if err != nil || affected != 1 {
+		t.Fatalf("UpdateWhere = %d, %v", affected, err)
+	}
+	var got Part
+	if err := repo.GetByID(ctx, &Part{}, part.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Version != 3 || got.Name != "Hex nut" || got.Stock != 3 {
+		t.Errorf("expected Hex nut with stock 3 at version 3, got %+v", got)
+	}
+}
+
+func TestPartRepository_DeleteVersioned(t *testing.T) {
+	db := setupTestDB(t, &Part{})
+	repo := New(db)
+	ctx := context.Background()
+
+	part := Part{Name: "Washer", Stock: 100}
+	if err := repo.Create(ctx, &part); err != nil {
+		t.Fatalf("failed to create part: %v", err)
+	}
+	stale := part
+
+	part.Stock = 99
+	if err := repo.Update(ctx, &part); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := repo.Delete(ctx, &stale); !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("expected ErrStaleObject, got %v", err)
+	}
+	if err := repo.Delete(ctx, &part); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+}
+
+func TestPartRepository_VersionedZeroKey(t *testing.T) {
+	db := setupTestDB(t, &Part{})
+	repo := New(db)
+	ctx := context.Background()
+
+	for _, name := range []string{"Bolt", "Nut"} {
+		if err := repo.Create(ctx, &Part{Name: name, Stock: 1}); err != nil {
+			t.Fatalf("failed to create part: %v", err)
+		}
+	}
+
+	if err := repo.Delete(ctx, &Part{}); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("Delete: expected ErrMissingWhereClause, got %v", err)
+	}
+	if err := repo.UpdateFields(ctx, &Part{Stock: 5}, "Stock"); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("UpdateFields: expected ErrMissingWhereClause, got %v", err)
+	}
+	if err := repo.UpdateMap(ctx, &Part{}, map[string]any{"stock": 7}); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("UpdateMap: expected ErrMissingWhereClause, got %v", err)
+	}
+
+	var parts []Part
+	if err := repo.GetAll(ctx, &Part{}, &parts); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(parts) != 2 || parts[0].Stock != 1 || parts[1].Stock != 1 || parts[0].Version != 0 {
+		t.Errorf("expected the parts to be untouched, got %+v", parts)
+	}
+}
+
+func TestEngineRepository_VersionedInterface(t *testing.T) {
+	db := setupTestDB(t, &Engine{})
+	repo := New(db)
+	ctx := context.Background()
+
+	engine := Engine{Code: "V8"}
+	if err := repo.Create(ctx, &engine); err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	stale := engine
+
+	engine.Code = "V8 turbo"
+	if err := repo.Update(ctx, &engine); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if engine.Revision != 1 {
+		t.Errorf("expected revision 1, got %d", engine.Revision)
+	}
+	if err := repo.Update(ctx, &stale); !errors.Is(err, ErrStaleObject) {
+		t.Errorf("expected ErrStaleObject, got %v", err)
+	}
+}
@@ -0,0 +1,329 @@
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// tagName is the struct tag marking the tenant column, e.g. `gormr:"tenant"`.
+const tagName = "gormr"
+
+// Dialects able to restrict an upsert's update to the tenant's rows.
+var upsertWhereDialects = []string{"postgres", "sqlite"}
+
+// Strategy selects how tenants are isolated.
+type Strategy int
+
+const (
+	// StrategyColumn filters the rows of models with a tenant column on that column.
+	StrategyColumn Strategy = iota
+	// StrategySchema qualifies every table with the schema of the tenant (a database on MySQL).
+	StrategySchema
+)
+
+// Config configures the tenancy Plugin. Zero values select the defaults.
+type Config struct {
+	// Isolation strategy (default StrategyColumn)
+	Strategy Strategy
+	// Tenant column of StrategyColumn (default "tenant_id"). A field tagged `gormr:"tenant"` is used too.
+	Column string
+	// Schema holding the tables of a tenant with StrategySchema (default: the tenant itself)
+	SchemaName func(tenant string) string
+	// Tables StrategySchema leaves unqualified, shared by every tenant
+	SharedTables []string
+}
+
+// Plugin is a GORM plugin scoping statements to the tenant in their context.
+//
+// With StrategyColumn, statements on models having a tenant column are scoped: reads, updates and
+// deletes are filtered on the column and creates set it. With StrategySchema, the tables of every
+// model-based statement are qualified with the tenant's schema. Either way it fails closed: scoped
+// statements without a tenant in context fail with ErrNoTenant, unless the context was created by
+// WithoutTenant. Raw SQL is never rewritten.
+type Plugin struct {
+	cfg Config
+}
+
+// New creates the tenancy Plugin; register it with db.Use.
+func New(cfg Config) *Plugin {
+	if cfg.Column == "" {
+		cfg.Column = "tenant_id"
+	}
+	if cfg.SchemaName == nil {
+		cfg.SchemaName = func(tenant string) string { return tenant }
+	}
+	return &Plugin{cfg: cfg}
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string {
+	return "gormr:tenancy"
+}
+
+// Initialize registers the plugin callbacks on db.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errorsOf(
+		cb.Create().Before("gorm:before_create").Register("gormr:tenancy_create", p.create),
+		cb.Query().Before("gorm:query").Register("gormr:tenancy_query", p.filter),
+		cb.Row().Before("gorm:row").Register("gormr:tenancy_row", p.filter),
+		cb.Update().Before("gorm:update").Register("gormr:tenancy_update", p.update),
+		cb.Update().After("gorm:update").Register("gormr:tenancy_updated", p.updated),
+		cb.Delete().Before("gorm:delete").Register("gormr:tenancy_delete", p.delete),
+	)
+}
+
+func errorsOf(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenant returns the tenant the statement must be scoped to, and false when it must not be scoped.
+// Statements to scope without a tenant in context get ErrNoTenant.
+func (p *Plugin) tenant(db *gorm.DB) (string, bool) {
+	ctx := db.Statement.Context
+	if db.Error != nil || db.Statement.Schema == nil || bypassed(ctx) {
+		return "", false
+	}
+	if p.cfg.Strategy == StrategySchema && slices.Contains(p.cfg.SharedTables, db.Statement.Schema.Table) {
+		return "", false
+	}
+	if p.cfg.Strategy == StrategyColumn && p.field(db.Statement.Schema) == nil {
+		return "", false
+	}
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		db.AddError(ErrNoTenant)
+		return "", false
+	}
+	return tenant, true
+}
+
+// field returns the tenant field of s, or nil when the model is not tenant-scoped.
+func (p *Plugin) field(s *schema.Schema) *schema.Field {
+	for _, f := range s.Fields {
+		if _, ok := schema.ParseTagSetting(f.Tag.Get(tagName), ";")["TENANT"]; ok {
+			return f
+		}
+	}
+	return s.LookUpField(p.cfg.Column)
+}
+
+// qualify points the statement to the tables of tenant (StrategySchema).
+func (p *Plugin) qualify(db *gorm.DB, tenant string) bool {
+	name := p.cfg.SchemaName(tenant)
+	if !identifier.MatchString(name) {
+		db.AddError(fmt.Errorf("%w: %q", ErrInvalidTenant, name))
+		return false
+	}
+	db.Statement.Table = name + "." + db.Statement.Schema.Table
+	return true
+}
+
+// filter scopes reads to the tenant.
+func (p *Plugin) filter(db *gorm.DB) {
+	tenant, ok := p.tenant(db)
+	if !ok {
+		return
+	}
+	if p.cfg.Strategy == StrategySchema {
+		p.qualify(db, tenant)
+		return
+	}
+	p.where(db, tenant)
+}
+
+// where adds the tenant condition to the statement.
+func (p *Plugin) where(db *gorm.DB, tenant string) {
+	f := p.field(db.Statement.Schema)
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: tenant},
+	}})
+}
+
+// create sets the tenant of the inserted entities.
+func (p *Plugin) create(db *gorm.DB) {
+	tenant, ok := p.tenant(db)
+	if !ok {
+		return
+	}
+	if p.cfg.Strategy == StrategySchema {
+		p.qualify(db, tenant)
+		return
+	}
+	if !p.assign(db, tenant) {
+		return
+	}
+	if c, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+		p.restrictUpsert(db, c, tenant)
+	}
+}
+
+// restrictUpsert keeps an upsert from updating a conflicting row of another tenant.
+func (p *Plugin) restrictUpsert(db *gorm.DB, c clause.Clause, tenant string) {
+	conflict, _ := c.Expression.(clause.OnConflict)
+	if conflict.DoNothing || (!conflict.UpdateAll && len(conflict.DoUpdates) == 0) {
+		return
+	}
+	if !slices.Contains(upsertWhereDialects, db.Dialector.Name()) {
+		db.AddError(ErrTenantUpsert)
+		return
+	}
+	f := p.field(db.Statement.Schema)
+	conflict.Where.Exprs = append(conflict.Where.Exprs, clause.Eq{
+		Column: clause.Column{Table: db.Statement.Table, Name: f.DBName},
+		Value:  tenant,
+	})
+	db.Statement.AddClause(conflict)
+}
+
+// update scopes updates to the tenant.
+func (p *Plugin) update(db *gorm.DB) {
+	tenant, ok := p.tenant(db)
+	if !ok {
+		return
+	}
+	if p.cfg.Strategy == StrategySchema {
+		p.qualify(db, tenant)
+		return
+	}
+	if !p.assign(db, tenant) || !p.checkChanges(db, tenant) {
+		return
+	}
+	if hasConditions(db) {
+		p.where(db, tenant)
+	}
+}
+
+// updated fails the updates of an entity of another tenant with ErrTenantMismatch: filtered on
+// the tenant they match no row, which Save would otherwise take for a new entity and upsert.
+func (p *Plugin) updated(db *gorm.DB) {
+	if db.Error != nil || db.RowsAffected > 0 || p.cfg.Strategy != StrategyColumn {
+		return
+	}
+	tenant, ok := p.tenant(db)
+	if !ok {
+		return
+	}
+	s, rv := db.Statement.Schema, db.Statement.ReflectValue
+	if rv.Kind() != reflect.Struct || rv.Type() != s.ModelType || len(s.PrimaryFields) == 0 {
+		return
+	}
+	ctx := db.Statement.Context
+	q := db.Session(&gorm.Session{NewDB: true, Context: WithoutTenant(ctx)}).Table(db.Statement.Table)
+	for _, pf := range s.PrimaryFields {
+		v, zero := pf.ValueOf(ctx, rv)
+		if zero {
+			return
+		}
+		q = q.Where(clause.Eq{Column: clause.Column{Name: pf.DBName}, Value: v})
+	}
+	var tenants []string
+	if err := q.Pluck(p.field(s).DBName, &tenants).Error; err != nil {
+		db.AddError(err)
+		return
+	}
+	if len(tenants) > 0 && !slices.Contains(tenants, tenant) {
+		db.AddError(ErrTenantMismatch)
+	}
+}
+
+// delete scopes deletes to the tenant.
+func (p *Plugin) delete(db *gorm.DB) {
+	tenant, ok := p.tenant(db)
+	if !ok {
+		return
+	}
+	if p.cfg.Strategy == StrategySchema {
+		p.qualify(db, tenant)
+		return
+	}
+	if hasConditions(db) {
+		p.where(db, tenant)
+	}
+}
+
+// assign sets the tenant field of the entities written by the statement, failing if one belongs to
+// another tenant.
+func (p *Plugin) assign(db *gorm.DB, tenant string) bool {
+	f := p.field(db.Statement.Schema)
+	ctx := db.Statement.Context
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range rv.Len() {
+			if !p.assignOne(ctx, db, f, reflect.Indirect(rv.Index(i)), tenant) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		return p.assignOne(ctx, db, f, rv, tenant)
+	}
+	return true
+}
+
+func (p *Plugin) assignOne(ctx context.Context, db *gorm.DB, f *schema.Field, rv reflect.Value, tenant string) bool {
+	if rv.Kind() != reflect.Struct || rv.Type() != db.Statement.Schema.ModelType {
+		return true
+	}
+	if v, zero := f.ValueOf(ctx, rv); !zero {
+		if fmt.Sprint(v) != tenant {
+			db.AddError(ErrTenantMismatch)
+			return false
+		}
+		return true
+	}
+	if err := f.Set(ctx, rv, tenant); err != nil {
+		db.AddError(err)
+		return false
+	}
+	return true
+}
+
+// checkChanges rejects map updates moving rows to another tenant.
+func (p *Plugin) checkChanges(db *gorm.DB, tenant string) bool {
+	changes, ok := db.Statement.Dest.(map[string]any)
+	if !ok {
+		return true
+	}
+	f := p.field(db.Statement.Schema)
+	for _, key := range []string{f.DBName, f.Name} {
+		if v, ok := changes[key]; ok && fmt.Sprint(v) != tenant {
+			db.AddError(ErrTenantMismatch)
+			return false
+		}
+	}
+	return true
+}
+
+// hasConditions reports whether an update or delete targets specific rows, through a WHERE clause
+// or the primary key of its model. Global statements are left unscoped so GORM still rejects
+// them with gorm.ErrMissingWhereClause.
+func hasConditions(db *gorm.DB) bool {
+	if db.AllowGlobalUpdate {
+		return true
+	}
+	if _, ok := db.Statement.Clauses["WHERE"]; ok {
+		return true
+	}
+	ctx := db.Statement.Context
+	for _, v := range []reflect.Value{db.Statement.ReflectValue, reflect.ValueOf(db.Statement.Model)} {
+		if !v.IsValid() {
+			continue
+		}
+		if _, values := schema.GetIdentityFieldValuesMap(ctx, reflect.Indirect(v), db.Statement.Schema.PrimaryFields); len(values) > 0 {
+			return true
+		}
+	}
+	return false
+}
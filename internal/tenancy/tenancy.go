@@ -0,0 +1,54 @@
+// Package tenancy scopes every statement to the tenant carried by the context, as a GORM plugin.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+var (
+	// ErrNoTenant is returned by statements on tenant-scoped models run without a tenant in context.
+	ErrNoTenant = errors.New("gormr: no tenant in context")
+	// ErrTenantMismatch is returned when writing an entity, or a tenant column, of another tenant.
+	ErrTenantMismatch = errors.New("gormr: entity belongs to another tenant")
+	// ErrInvalidTenant is returned by StrategySchema for tenants whose schema name is not a plain identifier.
+	ErrInvalidTenant = errors.New("gormr: invalid tenant schema name")
+	// ErrTenantUpsert is returned by upserts on tenant-scoped models on dialects that cannot restrict
+	// the conflicting row to the tenant (MySQL, SQL Server).
+	ErrTenantUpsert = errors.New("gormr: upsert on a tenant-scoped model is not supported by this dialect")
+)
+
+// identifier matches the schema names StrategySchema accepts.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type tenantKey struct{}
+
+type bypassKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant every statement run with it is scoped to.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by ctx, if any. It reports none for contexts
+// created by WithoutTenant, whose statements are not scoped.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if bypassed(ctx) {
+		return "", false
+	}
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// WithoutTenant returns a copy of ctx whose statements are not scoped to any tenant, for
+// cross-tenant jobs such as migrations or reporting. Use it sparingly: it disables the isolation.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// bypassed reports whether ctx was created by WithoutTenant.
+func bypassed(ctx context.Context) bool {
+	b, _ := ctx.Value(bypassKey{}).(bool)
+	return b
+}
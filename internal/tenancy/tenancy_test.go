@@ -0,0 +1,232 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+type Invoice struct {
+	ID       uint `gorm:"primaryKey"`
+	TenantID string
+	Amount   int
+}
+
+type Ticket struct {
+	ID    uint   `gorm:"primaryKey"`
+	Org   string `gormr:"tenant"`
+	Title string
+}
+
+type Currency struct {
+	Code string `gorm:"primaryKey"`
+	Name string
+}
+
+func setupTenancy(t *testing.T) (*gorm.DB, *repository.Repository) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&Invoice{}, &Ticket{}, &Currency{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if err := db.Use(New(Config{})); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	return db, repository.New(db)
+}
+
+func TestTenancy_ScopesReads(t *testing.T) {
+	_, repo := setupTenancy(t)
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+
+	for _, c := range []struct {
+		ctx    context.Context
+		amount int
+	}{{acme, 10}, {acme, 20}, {globex, 30}} {
+		if err := repo.Create(c.ctx, &Invoice{Amount: c.amount}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	var invoices []Invoice
+	if err := repo.GetAll(acme, &Invoice{}, &invoices); err != nil {
+		t.Fatalf("GetAll failed: %v", err)
+	}
+	if len(invoices) != 2 || invoices[0].TenantID != "acme" || invoices[1].TenantID != "acme" {
+		t.Errorf("expected the 2 invoices of acme, got %+v", invoices)
+	}
+
+	n, err := repo.Count(globex, repository.NewSpec(&Invoice{}))
+	if err != nil || n != 1 {
+		t.Errorf("expected 1 invoice for globex, got %d (%v)", n, err)
+	}
+
+	var inv Invoice
+	if err := repo.GetByID(globex, &Invoice{}, invoices[0].ID, &inv); err != nil || inv.ID != 0 {
+		t.Errorf("expected another tenant's invoice not to be found, got %+v (%v)", inv, err)
+	}
+
+	n, err = repo.Count(WithoutTenant(context.Background()), repository.NewSpec(&Invoice{}))
+	if err != nil || n != 3 {
+		t.Errorf("expected 3 invoices without tenant, got %d (%v)", n, err)
+	}
+}
+
+func TestTenancy_FailsClosed(t *testing.T) {
+	_, repo := setupTenancy(t)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &Invoice{Amount: 1}); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("expected ErrNoTenant on Create, got %v", err)
+	}
+	var invoices []Invoice
+	if err := repo.GetAll(ctx, &Invoice{}, &invoices); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("expected ErrNoTenant on GetAll, got %v", err)
+	}
+	if _, err := repo.DeleteWhere(ctx, repository.NewSpec(&Invoice{}).Eq("amount", 1)); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("expected ErrNoTenant on DeleteWhere, got %v", err)
+	}
+
+	// Models without tenant column are not scoped
+	if err := repo.Create(ctx, &Currency{Code: "EUR", Name: "Euro"}); err != nil {
+		t.Errorf("expected Create of an unscoped model to succeed, got %v", err)
+	}
+}
+
+func TestTenancy_ScopesWrites(t *testing.T) {
+	db, repo := setupTenancy(t)
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+
+	inv := &Invoice{Amount: 10}
+	if err := repo.Create(acme, inv); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.Create(globex, &Invoice{TenantID: "acme"}); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("expected ErrTenantMismatch creating for another tenant, got %v", err)
+	}
+
+	n, err := repo.UpdateWhere(globex, repository.NewSpec(&Invoice{}).Eq("id", inv.ID), map[string]any{"amount": 99})
+	if err != nil || n != 0 {
+		t.Errorf("expected no row updated by another tenant, got %d (%v)", n, err)
+	}
+	if err := repo.UpdateMap(acme, &Invoice{ID: inv.ID}, map[string]any{"tenant_id": "globex"}); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("expected ErrTenantMismatch moving a row to another tenant, got %v", err)
+	}
+	if err := repo.UpdateMap(acme, &Invoice{ID: inv.ID}, map[string]any{"amount": 11}); err != nil {
+		t.Errorf("UpdateMap failed: %v", err)
+	}
+	if err := repo.Update(globex, &Invoice{ID: inv.ID, Amount: 50}); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("expected ErrTenantMismatch updating another tenant's invoice, got %v", err)
+	}
+	if err := repo.UpdateMap(globex, &Invoice{ID: inv.ID}, map[string]any{"amount": 50}); !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("expected ErrTenantMismatch updating another tenant's invoice, got %v", err)
+	}
+	// Saving a new entity with its primary key set still inserts it
+	if err := repo.Update(globex, &Invoice{ID: inv.ID + 100, Amount: 5}); err != nil {
+		t.Errorf("expected Update of a new entity to insert it, got %v", err)
+	}
+
+	if err := repo.DeleteByID(globex, &Invoice{}, inv.ID); err != nil {
+		t.Fatalf("DeleteByID failed: %v", err)
+	}
+	var got Invoice
+	if err := repo.GetByID(acme, &Invoice{}, inv.ID, &got); err != nil || got.Amount != 11 {
+		t.Errorf("expected the invoice to survive another tenant's delete with amount 11, got %+v (%v)", got, err)
+	}
+
+	// Global statements are still rejected by GORM
+	if err := db.WithContext(acme).Delete(&Invoice{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("expected ErrMissingWhereClause, got %v", err)
+	}
+}
+
+func TestTenancy_Upsert(t *testing.T) {
+	_, repo := setupTenancy(t)
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+
+	inv := &Invoice{Amount: 10}
+	if err := repo.Create(acme, inv); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := repo.Upsert(globex, &Invoice{ID: inv.ID, Amount: 99}, repository.UpsertOptions{}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	var got Invoice
+	if err := repo.GetByID(acme, &Invoice{}, inv.ID, &got); err != nil || got.Amount != 10 {
+		t.Errorf("expected another tenant's upsert to leave the invoice untouched, got %+v (%v)", got, err)
+	}
+	if _, err := repo.Upsert(acme, &Invoice{ID: inv.ID, Amount: 12}, repository.UpsertOptions{}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if err := repo.GetByID(acme, &Invoice{}, inv.ID, &got); err != nil || got.Amount != 12 {
+		t.Errorf("expected upsert to update the invoice, got %+v (%v)", got, err)
+	}
+}
+
+func TestTenancy_TaggedColumn(t *testing.T) {
+	_, repo := setupTenancy(t)
+	acme := WithTenant(context.Background(), "acme")
+
+	ticket := &Ticket{Title: "broken"}
+	if err := repo.Create(acme, ticket); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if ticket.Org != "acme" {
+		t.Errorf("expected Org to be set to acme, got %q", ticket.Org)
+	}
+	var tickets []Ticket
+	if err := repo.GetAll(WithTenant(context.Background(), "globex"), &Ticket{}, &tickets); err != nil || len(tickets) != 0 {
+		t.Errorf("expected no ticket for globex, got %+v (%v)", tickets, err)
+	}
+}
+
+func TestTenancy_SchemaStrategy(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	cfg := Config{
+		Strategy:     StrategySchema,
+		SchemaName:   func(tenant string) string { return "tenant_" + tenant },
+		SharedTables: []string{"currencies"},
+	}
+	if err := db.Use(New(cfg)); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	ctx := WithTenant(context.Background(), "acme")
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var invoices []Invoice
+		return tx.WithContext(ctx).Where("amount > ?", 5).Find(&invoices)
+	})
+	if !strings.Contains(sql, "`tenant_acme`.`invoices`") {
+		t.Errorf("expected the invoices of the tenant schema, got %s", sql)
+	}
+	sql = db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var currencies []Currency
+		return tx.WithContext(ctx).Find(&currencies)
+	})
+	if strings.Contains(sql, "tenant_acme") {
+		t.Errorf("expected shared table to stay unqualified, got %s", sql)
+	}
+
+	var invoices []Invoice
+	err = db.WithContext(WithTenant(context.Background(), "acme; DROP")).Find(&invoices).Error
+	if !errors.Is(err, ErrInvalidTenant) {
+		t.Errorf("expected ErrInvalidTenant, got %v", err)
+	}
+	if err := db.Find(&invoices).Error; !errors.Is(err, ErrNoTenant) {
+		t.Errorf("expected ErrNoTenant, got %v", err)
+	}
+}
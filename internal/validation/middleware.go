@@ -0,0 +1,131 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// Middleware returns the repository middleware validating updates before they are written.
+//
+// Update validates the whole entities, tags and Validator interface. UpdateFields validates the
+// tag rules of the updated fields only, UpdateMap and UpdateWhere those of the changed columns
+// (expressions are not checked). Invalid writes fail with a *ValidationError without reaching the
+// database. Created entities are validated by the plugin (see Initialize), once the fields filled
+// on create are set.
+func (v *Validation) Middleware() repository.Middleware {
+	return func(next repository.Handler) repository.Handler {
+		return func(ctx context.Context, op *repository.Operation) (any, error) {
+			if err := v.operation(ctx, op); err != nil {
+				return nil, err
+			}
+			return next(ctx, op)
+		}
+	}
+}
+
+// operation validates the entities written by op.
+func (v *Validation) operation(ctx context.Context, op *repository.Operation) error {
+	var errs []FieldError
+	var err error
+	switch op.Name {
+	case repository.OpUpdate:
+		err = v.check(ctx, op.Args[0], nil, &errs)
+	case repository.OpUpdateFields:
+		if fields, ok := op.Args[1].([]string); ok {
+			err = v.fields(ctx, op, fields, &errs)
+		}
+	case repository.OpUpdateMap, repository.OpUpdateWhere:
+		if changes, ok := op.Args[1].(map[string]any); ok {
+			err = v.changes(ctx, op, changes, &errs)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return asError(errs)
+}
+
+// batch validates every entity of entities, a slice or pointer to a slice, prefixing the
+// fields with the entity index.
+func (v *Validation) batch(ctx context.Context, entities any, errs *[]FieldError) error {
+	rv := reflect.Indirect(reflect.ValueOf(entities))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return v.check(ctx, entities, nil, errs)
+	}
+	for i := range rv.Len() {
+		e := rv.Index(i)
+		if e.Kind() != reflect.Pointer && e.CanAddr() {
+			e = e.Addr()
+		}
+		n := len(*errs)
+		if err := v.check(ctx, e.Interface(), nil, errs); err != nil {
+			return err
+		}
+		for j := n; j < len(*errs); j++ {
+			f := &(*errs)[j]
+			if f.Field == "" {
+				f.Field = fmt.Sprintf("[%d]", i)
+			} else {
+				f.Field = fmt.Sprintf("[%d].%s", i, f.Field)
+			}
+		}
+	}
+	return nil
+}
+
+// fields validates the given fields (struct field or column names) of the entity of op.
+func (v *Validation) fields(ctx context.Context, op *repository.Operation, fields []string, errs *[]FieldError) error {
+	if v.cfg.NoTags {
+		return nil
+	}
+	s, err := op.Repo.Schema(op.Model)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(fields))
+	for _, name := range fields {
+		if f := s.LookUpField(name); f != nil {
+			names = append(names, f.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return v.check(ctx, op.Args[0], names, errs)
+}
+
+// changes validates the values of changes (column or struct field name -> value) against the
+// rules of their fields, set on a blank entity of the model of op.
+func (v *Validation) changes(ctx context.Context, op *repository.Operation, changes map[string]any, errs *[]FieldError) error {
+	if v.cfg.NoTags {
+		return nil
+	}
+	s, err := op.Repo.Schema(op.Model)
+	if err != nil {
+		return err
+	}
+	entity := reflect.New(s.ModelType)
+	var names []string
+	for key, value := range changes {
+		f := s.LookUpField(key)
+		if f == nil {
+			continue
+		}
+		if _, ok := value.(clause.Expression); ok {
+			continue
+		}
+		if err := f.Set(ctx, entity.Elem(), value); err != nil {
+			continue
+		}
+		names = append(names, f.Name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return v.check(ctx, entity.Interface(), names, errs)
+}
@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// Name returns the name of the plugin validating created entities.
+func (v *Validation) Name() string {
+	return "gormr:validation"
+}
+
+// Initialize registers the create callback validating the entities. It runs after the fields
+// filled on create (generated IDs, actor and tenant columns, BeforeCreate hooks) are set, so
+// required rules see them, and fails the statement with a *ValidationError before it reaches the
+// database. It covers Create, CreateBatch and Upsert.
+func (v *Validation) Initialize(db *gorm.DB) error {
+	return db.Callback().Create().After("gorm:before_create").Before("gorm:create").
+		Register("gormr:validation_create", v.create)
+}
+
+// create validates the entity or entities created by db.
+func (v *Validation) create(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || !stmt.ReflectValue.IsValid() {
+		return
+	}
+	var errs []FieldError
+	var err error
+	switch rv := stmt.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		err = v.batch(stmt.Context, rv.Interface(), &errs)
+	case reflect.Struct:
+		if rv.CanAddr() {
+			rv = rv.Addr()
+		}
+		err = v.check(stmt.Context, rv.Interface(), nil, &errs)
+	default:
+		return
+	}
+	if err == nil {
+		err = asError(errs)
+	}
+	if err != nil {
+		_ = db.AddError(err)
+	}
+}
@@ -0,0 +1,180 @@
+// Package validation checks entities before they are written through a Repository, with
+// `validate` struct tags (github.com/go-playground/validator) and the Validator interface.
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrValidation matches every ValidationError with errors.Is.
+var ErrValidation = errors.New("gormr: validation failed")
+
+// Validator is implemented by models checking invariants struct tags cannot express. Validate
+// may return a *ValidationError to report several fields; any other error is reported as a
+// violation of the entity as a whole.
+type Validator interface {
+	Validate(ctx context.Context) error
+}
+
+// FieldError is a rule violated by a field.
+type FieldError struct {
+	// Path of the field from the entity, e.g. "Email" or "Address.City" ("[2].Email" for the
+	// third entity of a batch); empty for violations of the entity as a whole
+	Field string
+	// Violated tag rule (e.g. "required" or "min"), empty for Validator errors
+	Rule string
+	// Parameter of the rule (e.g. "3" for min=3)
+	Param string
+	// Description of the violation
+	Message string
+}
+
+// Error formats the violation as "Field: message".
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every violation found in the entities of a write.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error lists the violations.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return ErrValidation.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrValidation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Rule is a custom tag rule: it reports whether value satisfies the rule given param (the text
+// after '=' in the tag, if any).
+type Rule func(value any, param string) bool
+
+// Config configures the validation. Zero values select the defaults.
+type Config struct {
+	// Struct tag holding the rules (default "validate")
+	TagName string
+	// Custom rules usable in tags, by name
+	Rules map[string]Rule
+	// Skip the struct tags and only call the Validator interface
+	NoTags bool
+}
+
+// Validation checks entities against their struct tags and Validator implementation.
+type Validation struct {
+	cfg      Config
+	validate *validator.Validate
+}
+
+// New creates a Validation. It fails if a custom rule cannot be registered.
+func New(cfg Config) (*Validation, error) {
+	if cfg.TagName == "" {
+		cfg.TagName = "validate"
+	}
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.SetTagName(cfg.TagName)
+	for name, rule := range cfg.Rules {
+		err := v.RegisterValidation(name, func(fl validator.FieldLevel) bool {
+			return rule(fl.Field().Interface(), fl.Param())
+		})
+		if err != nil {
+			return nil, fmt.Errorf("gormr: invalid validation rule %q: %w", name, err)
+		}
+	}
+	return &Validation{cfg: cfg, validate: v}, nil
+}
+
+// Struct validates entity (a struct or pointer to one). It returns a *ValidationError listing
+// every violation, or nil.
+func (v *Validation) Struct(ctx context.Context, entity any) error {
+	var errs []FieldError
+	if err := v.check(ctx, entity, nil, &errs); err != nil {
+		return err
+	}
+	return asError(errs)
+}
+
+// check appends the violations of entity to errs; fields restricts the tag rules to these struct
+// fields and skips the Validator interface. Unexpected errors are returned.
+func (v *Validation) check(ctx context.Context, entity any, fields []string, errs *[]FieldError) error {
+	rv := reflect.Indirect(reflect.ValueOf(entity))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	if !v.cfg.NoTags {
+		var err error
+		if fields == nil {
+			err = v.validate.StructCtx(ctx, entity)
+		} else {
+			err = v.validate.StructPartialCtx(ctx, entity, fields...)
+		}
+		if err := fieldErrors(err, errs); err != nil {
+			return err
+		}
+	}
+	if fields != nil {
+		return nil
+	}
+	m, ok := entity.(Validator)
+	if !ok && rv.CanAddr() {
+		m, ok = rv.Addr().Interface().(Validator)
+	}
+	if !ok {
+		return nil
+	}
+	err := m.Validate(ctx)
+	var verr *ValidationError
+	switch {
+	case err == nil:
+	case errors.As(err, &verr):
+		*errs = append(*errs, verr.Fields...)
+	default:
+		*errs = append(*errs, FieldError{Message: err.Error()})
+	}
+	return nil
+}
+
+// fieldErrors appends the violations reported by the validator to errs.
+func fieldErrors(err error, errs *[]FieldError) error {
+	var verrs validator.ValidationErrors
+	if err == nil {
+		return nil
+	}
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	for _, fe := range verrs {
+		// Drop the struct name heading the namespace
+		_, path, _ := strings.Cut(fe.StructNamespace(), ".")
+		msg := "failed on " + fe.Tag()
+		if fe.Param() != "" {
+			msg += "=" + fe.Param()
+		}
+		*errs = append(*errs, FieldError{Field: path, Rule: fe.Tag(), Param: fe.Param(), Message: msg})
+	}
+	return nil
+}
+
+// asError returns the ValidationError listing errs, or nil if there is none.
+func asError(errs []FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: errs}
+}
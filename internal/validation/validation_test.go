@@ -0,0 +1,234 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/alejandro-sotelo/gormr/internal/defaults"
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+	"github.com/alejandro-sotelo/gormr/internal/tenancy"
+)
+
+type Customer struct {
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `validate:"required"`
+	Email string `validate:"required,email"`
+	Age   int    `validate:"gte=0,lte=150"`
+	Plan  string `validate:"omitempty,plan"`
+}
+
+type Booking struct {
+	ID   uint `gorm:"primaryKey"`
+	From int
+	To   int
+}
+
+func (b *Booking) Validate(context.Context) error {
+	if b.To < b.From {
+		return &ValidationError{Fields: []FieldError{{Field: "To", Message: "must not be before From"}}}
+	}
+	return nil
+}
+
+func setupValidation(t *testing.T) *repository.Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&Customer{}, &Booking{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	v, err := New(Config{Rules: map[string]Rule{
+		"plan": func(value any, _ string) bool { return value == "free" || value == "pro" },
+	}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := db.Use(v); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	repo := repository.New(db)
+	repo.Use(v.Middleware())
+	return repo
+}
+
+func fieldsOf(t *testing.T, err error) []string {
+	t.Helper()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("expected the error to match ErrValidation")
+	}
+	var fields []string
+	for _, f := range verr.Fields {
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+func TestValidation_Create(t *testing.T) {
+	repo := setupValidation(t)
+	ctx := context.Background()
+
+	err := repo.Create(ctx, &Customer{Email: "nope", Age: 200, Plan: "gold"})
+	if got := strings.Join(fieldsOf(t, err), ","); got != "Name,Email,Age,Plan" {
+		t.Errorf("expected every violation to be listed, got %s (%v)", got, err)
+	}
+	n, _ := repo.Count(ctx, repository.NewSpec(&Customer{}))
+	if n != 0 {
+		t.Errorf("expected nothing to be written, got %d rows", n)
+	}
+
+	if err := repo.Create(ctx, &Customer{Name: "Ann", Email: "ann@example.com", Plan: "pro"}); err != nil {
+		t.Errorf("expected a valid customer to be created, got %v", err)
+	}
+}
+
+func TestValidation_Validator(t *testing.T) {
+	repo := setupValidation(t)
+	ctx := context.Background()
+
+	err := repo.Create(ctx, &Booking{From: 5, To: 1})
+	if got := fieldsOf(t, err); len(got) != 1 || got[0] != "To" {
+		t.Errorf("expected the Validator violation, got %v", got)
+	}
+	b := &Booking{From: 1, To: 5}
+	if err := repo.Create(ctx, b); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	b.To = 0
+	if err := repo.Update(ctx, b); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected Update to be validated, got %v", err)
+	}
+}
+
+func TestValidation_Batch(t *testing.T) {
+	repo := setupValidation(t)
+	customers := []Customer{
+		{Name: "Ann", Email: "ann@example.com"},
+		{Name: "Bob"},
+	}
+	err := repo.CreateBatch(context.Background(), &customers, 10)
+	if got := fieldsOf(t, err); len(got) != 1 || got[0] != "[1].Email" {
+		t.Errorf("expected the violation of the second customer, got %v", got)
+	}
+}
+
+func TestValidation_PartialUpdates(t *testing.T) {
+	repo := setupValidation(t)
+	ctx := context.Background()
+	c := &Customer{Name: "Ann", Email: "ann@example.com"}
+	if err := repo.Create(ctx, c); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Only the updated fields are checked
+	if err := repo.UpdateFields(ctx, &Customer{ID: c.ID, Age: 30}, "age"); err != nil {
+		t.Errorf("expected UpdateFields of a valid field to succeed, got %v", err)
+	}
+	err := repo.UpdateFields(ctx, &Customer{ID: c.ID, Email: "bad"}, "Email")
+	if got := fieldsOf(t, err); len(got) != 1 || got[0] != "Email" {
+		t.Errorf("expected the Email violation, got %v", got)
+	}
+
+	err = repo.UpdateMap(ctx, c, map[string]any{"email": "bad", "age": 31})
+	if got := fieldsOf(t, err); len(got) != 1 || got[0] != "Email" {
+		t.Errorf("expected the Email violation, got %v", got)
+	}
+	if err := repo.UpdateMap(ctx, c, map[string]any{"age": gorm.Expr("age + 1")}); err != nil {
+		t.Errorf("expected expressions to be skipped, got %v", err)
+	}
+	_, err = repo.UpdateWhere(ctx, repository.NewSpec(&Customer{}).Eq("id", c.ID), map[string]any{"name": ""})
+	if got := fieldsOf(t, err); len(got) != 1 || got[0] != "Name" {
+		t.Errorf("expected the Name violation, got %v", got)
+	}
+}
+
+func TestValidation_Struct(t *testing.T) {
+	v, err := New(Config{NoTags: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := v.Struct(context.Background(), &Customer{}); err != nil {
+		t.Errorf("expected tags to be skipped, got %v", err)
+	}
+	err = v.Struct(context.Background(), &Booking{From: 2, To: 1})
+	if err == nil || err.Error() != "gormr: validation failed: To: must not be before From" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := New(Config{Rules: map[string]Rule{"": nil}}); err == nil {
+		t.Errorf("expected an invalid rule name to be rejected")
+	}
+}
+
+// Ticket has required fields filled on create by the defaults and tenancy plugins.
+type Ticket struct {
+	ID        string `gorm:"primaryKey" gormr:"default:uuidv7" validate:"required"`
+	TenantID  string `validate:"required"`
+	CreatedBy string `validate:"required"`
+	Title     string `validate:"required"`
+}
+
+func TestValidation_AfterFilledFields(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&Ticket{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	v, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	// Validation registered first: the callback order must not depend on it
+	for _, p := range []gorm.Plugin{v, tenancy.New(tenancy.Config{}), defaults.New(defaults.Config{})} {
+		if err := db.Use(p); err != nil {
+			t.Fatalf("failed to register %s: %v", p.Name(), err)
+		}
+	}
+	repo := repository.New(db)
+	repo.Use(v.Middleware())
+	ctx := repository.WithActor(tenancy.WithTenant(context.Background(), "acme"), "ann")
+
+	ticket := &Ticket{Title: "Printer on fire"}
+	if err := repo.Create(ctx, ticket); err != nil {
+		t.Fatalf("expected the filled fields to pass validation, got %v", err)
+	}
+	if ticket.ID == "" || ticket.TenantID != "acme" || ticket.CreatedBy != "ann" {
+		t.Errorf("expected the fields to be filled, got %+v", ticket)
+	}
+	if got := fieldsOf(t, repo.Create(ctx, &Ticket{})); len(got) != 1 || got[0] != "Title" {
+		t.Errorf("expected only the unfilled field to fail, got %v", got)
+	}
+}
+
+func TestValidation_ClearedArgs(t *testing.T) {
+	repo := setupValidation(t)
+	repo.Use(func(next repository.Handler) repository.Handler {
+		return func(ctx context.Context, op *repository.Operation) (any, error) {
+			for i := range op.Args {
+				op.Args[i] = nil
+			}
+			return next(ctx, op)
+		}
+	})
+	// The clearing middleware runs after validation; a validation middleware registered after it
+	// must not panic either
+	v, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	repo.Use(v.Middleware())
+	ctx := context.Background()
+	_ = repo.UpdateFields(ctx, &Customer{ID: 1}, "Name")
+	_, _ = repo.UpdateWhere(ctx, repository.NewSpec(&Customer{}).Eq("id", 1), map[string]any{"name": "x"})
+}
@@ -0,0 +1,22 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/audit"
+
+// Auditor reads the audit trail recorded once Client.EnableAudit ran.
+type Auditor = audit.Auditor
+
+// Auditable is implemented by models opting into the audit trail.
+type Auditable = audit.Auditable
+
+// AuditEntry is a change to an entity recorded in the gormr_audit table.
+type AuditEntry = audit.Entry
+
+// AuditChange is the value of a column before and after an operation.
+type AuditChange = audit.Change
+
+// Audited actions.
+const (
+	AuditCreate = audit.ActionCreate
+	AuditUpdate = audit.ActionUpdate
+	AuditDelete = audit.ActionDelete
+)
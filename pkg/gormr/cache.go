@@ -0,0 +1,18 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/cache"
+
+// CacheConfig configures Client.EnableCache.
+type CacheConfig = cache.Config
+
+// Cache stores the cached entities; implement it to use e.g. Redis.
+type Cache = cache.Cache
+
+// Cacher caches the entities read by GetByID, returned by Client.EnableCache.
+type Cacher = cache.Cacher
+
+// LRU is the default, in-memory Cache.
+type LRU = cache.LRU
+
+// NewLRU creates an LRU holding up to capacity entries.
+var NewLRU = cache.NewLRU
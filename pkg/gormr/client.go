@@ -1,17 +1,37 @@
 package gormr
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"gorm.io/gorm"
 
+	"github.com/alejandro-sotelo/gormr/internal/audit"
+	"github.com/alejandro-sotelo/gormr/internal/cache"
 	"github.com/alejandro-sotelo/gormr/internal/db"
+	"github.com/alejandro-sotelo/gormr/internal/defaults"
+	"github.com/alejandro-sotelo/gormr/internal/encryption"
+	"github.com/alejandro-sotelo/gormr/internal/lock"
+	"github.com/alejandro-sotelo/gormr/internal/outbox"
+	"github.com/alejandro-sotelo/gormr/internal/queue"
 	"github.com/alejandro-sotelo/gormr/internal/repository"
+	"github.com/alejandro-sotelo/gormr/internal/tenancy"
+	"github.com/alejandro-sotelo/gormr/internal/validation"
 )
 
 // Client is the main entry point for interacting with the gormr sdk.
 // It holds the database connection and repository helpers.
 type Client struct {
-	db   *gorm.DB
-	repo *repository.Repository
+	db     *gorm.DB
+	repo   *repository.Repository
+	locker *lock.Locker
+	// auditor is set once EnableAudit ran
+	auditor *audit.Auditor
+	// tenancy is set once EnableTenancy ran
+	tenancy bool
+	// unscopedCache is set once EnableCache ran without tenancy nor cfg.Scope
+	unscopedCache bool
 }
 
 type DBConfig = db.DBConfig
@@ -23,8 +43,9 @@ func New(cfg DBConfig) (*Client, error) {
 		return nil, err
 	}
 	return &Client{
-		db:   connection,
-		repo: repository.New(connection),
+		db:     connection,
+		repo:   repository.New(connection),
+		locker: lock.New(connection, lock.Config{}),
 	}, nil
 }
 
@@ -49,3 +70,115 @@ func (c *Client) DB() *gorm.DB {
 func (c *Client) Repo() *repository.Repository {
 	return c.repo
 }
+
+// Queue returns the job queue named name, stored in the gormr_jobs table.
+// Call Queue.Migrate once to create the table.
+func (c *Client) Queue(name string, cfg QueueConfig) *Queue {
+	return queue.New(c.repo, name, cfg)
+}
+
+// Lock takes the distributed lock called name for a lease of ttl, waiting until it is free or ctx is done.
+// The lease must be renewed with Lock.Renew to keep the lock longer than ttl.
+func (c *Client) Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	return c.locker.Lock(ctx, name, ttl)
+}
+
+// TryLock takes the distributed lock called name without waiting. It returns ErrLockHeld when
+// another owner holds it.
+func (c *Client) TryLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	return c.locker.TryLock(ctx, name, ttl)
+}
+
+// Outbox returns the transactional outbox, stored in the gormr_outbox table.
+// Call Outbox.Migrate once to create the table.
+func (c *Client) Outbox() *Outbox {
+	return outbox.New(c.repo)
+}
+
+// EnableAudit creates the gormr_audit table and records the changes made through Repo to models
+// implementing Auditable, tagged with the actor set by WithActor. Calling it again returns the
+// same Auditor, which also reads the history back.
+func (c *Client) EnableAudit(ctx context.Context) (*Auditor, error) {
+	if c.auditor != nil {
+		return c.auditor, nil
+	}
+	a := audit.New(c.repo)
+	if err := a.Migrate(ctx); err != nil {
+		return nil, err
+	}
+	c.repo.Use(a.Middleware())
+	c.auditor = a
+	return a, nil
+}
+
+// EnableTenancy scopes every statement run through the client to the tenant set by WithTenant.
+// With the default column strategy, models with a tenant column are filtered on it and get it set
+// on create; statements on them without a tenant in context fail with ErrNoTenant. It must be
+// called once, before the client is used, and before EnableCache, which keeps its entries per
+// tenant.
+func (c *Client) EnableTenancy(cfg TenancyConfig) error {
+	if c.unscopedCache {
+		return errors.New("gormr: EnableTenancy must be called before EnableCache, whose entries tenants would share")
+	}
+	if err := c.db.Use(tenancy.New(cfg)); err != nil {
+		return err
+	}
+	c.tenancy = true
+	return nil
+}
+
+// EnableValidation validates the entities written through Repo against their `validate` tags
+// and Validator implementation; invalid writes fail with a *ValidationError listing every
+// violation. Created entities are validated once the fields filled on create (EnableDefaults,
+// EnableTenancy) are set. It must be called once, before the client is used.
+func (c *Client) EnableValidation(cfg ValidationConfig) error {
+	v, err := validation.New(cfg)
+	if err != nil {
+		return err
+	}
+	if err := c.db.Use(v); err != nil {
+		return err
+	}
+	c.repo.Use(v.Middleware())
+	return nil
+}
+
+// EnableDefaults fills fields on write: `gormr:"default:<provider>"` fields (e.g. uuidv7, ulid or
+// snowflake primary keys) on create, and created_by/updated_by from the actor set by WithActor.
+// It must be called once, before the client is used.
+func (c *Client) EnableDefaults(cfg DefaultsConfig) error {
+	return c.db.Use(defaults.New(cfg))
+}
+
+// EnableEncryption encrypts the fields tagged `gormr:"encrypted"` with AES-GCM on write and
+// decrypts them on read, with the keys of cfg.Keys. GetByField searches the fields tagged
+// `gormr:"encrypted:deterministic"`; Encryptor.Lookup returns the values to search them with
+// otherwise. It must be called once, before the client is used.
+func (c *Client) EnableEncryption(cfg EncryptionConfig) (*Encryptor, error) {
+	e, err := encryption.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.db.Use(e); err != nil {
+		return nil, err
+	}
+	c.repo.Use(e.Middleware())
+	return e, nil
+}
+
+// EnableCache caches the entities read by Repo.GetByID, invalidating them on the writes made
+// through Repo once committed. With tenancy enabled and no cfg.Scope, entries are kept per tenant
+// and reads without a tenant skip the cache. It must be called once, before the client is used,
+// and after EnableTenancy.
+func (c *Client) EnableCache(cfg CacheConfig) *Cacher {
+	if cfg.Scope == nil && c.tenancy {
+		cfg.Scope = func(ctx context.Context) string {
+			tenant, _ := tenancy.TenantFromContext(ctx)
+			return tenant
+		}
+	}
+	c.unscopedCache = cfg.Scope == nil
+	cc := cache.New(cfg)
+	c.repo.Use(cc.Middleware())
+	return cc
+}
@@ -0,0 +1,55 @@
+package gormr
+
+import (
+	"context"
+	"testing"
+)
+
+type Invoice struct {
+	ID       uint `gorm:"primaryKey"`
+	TenantID string
+	Number   string
+}
+
+func newClient(t *testing.T) *Client {
+	t.Helper()
+	c, err := New(DBConfig{Driver: "sqlite", DBName: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.DB().AutoMigrate(&Invoice{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return c
+}
+
+func TestClient_CacheAndTenancy(t *testing.T) {
+	c := newClient(t)
+	c.EnableCache(CacheConfig{Models: []any{&Invoice{}}})
+	if err := c.EnableTenancy(TenancyConfig{}); err == nil {
+		t.Fatalf("expected EnableTenancy after EnableCache to fail")
+	}
+
+	c = newClient(t)
+	if err := c.EnableTenancy(TenancyConfig{}); err != nil {
+		t.Fatalf("EnableTenancy failed: %v", err)
+	}
+	c.EnableCache(CacheConfig{Models: []any{&Invoice{}}})
+	acme := WithTenant(context.Background(), "acme")
+	inv := &Invoice{Number: "A-1"}
+	if err := c.Repo().Create(acme, inv); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	var got Invoice
+	if err := c.Repo().GetByID(acme, &Invoice{}, inv.ID, &got); err != nil || got.Number != "A-1" {
+		t.Fatalf("expected acme's invoice, got %+v (%v)", got, err)
+	}
+	got = Invoice{}
+	if err := c.Repo().GetByID(WithTenant(context.Background(), "globex"), &Invoice{}, inv.ID, &got); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.ID != 0 {
+		t.Errorf("expected globex not to read acme's cached invoice, got %+v", got)
+	}
+}
@@ -0,0 +1,21 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/defaults"
+
+// DefaultsConfig configures Client.EnableDefaults: custom providers, snowflake node and clock.
+type DefaultsConfig = defaults.Config
+
+// DefaultProvider returns the default value of a field tagged `gormr:"default:<name>"`.
+type DefaultProvider = defaults.Provider
+
+// Built-in default providers.
+const (
+	ProviderUUIDv7    = defaults.ProviderUUIDv7
+	ProviderUUID      = defaults.ProviderUUID
+	ProviderULID      = defaults.ProviderULID
+	ProviderSnowflake = defaults.ProviderSnowflake
+	ProviderNow       = defaults.ProviderNow
+)
+
+// ErrClockBackwards is returned by the snowflake provider when the clock moved backwards.
+var ErrClockBackwards = defaults.ErrClockBackwards
@@ -0,0 +1,33 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/encryption"
+
+// EncryptionConfig configures Client.EnableEncryption.
+type EncryptionConfig = encryption.Config
+
+// Encryptor encrypts the fields tagged `gormr:"encrypted"`, returned by Client.EnableEncryption.
+type Encryptor = encryption.Encryptor
+
+// EncryptionKey is an AES key identified by the ID stored with the values it encrypts.
+type EncryptionKey = encryption.Key
+
+// KeyProvider supplies the encryption keys.
+type KeyProvider = encryption.KeyProvider
+
+// StaticKeys is a KeyProvider holding its keys in memory.
+type StaticKeys = encryption.StaticKeys
+
+// NewStaticKeys creates a StaticKeys encrypting with the key current among keys (ID -> secret).
+var NewStaticKeys = encryption.NewStaticKeys
+
+// ErrInvalidKey is returned for keys with an invalid ID or size.
+var ErrInvalidKey = encryption.ErrInvalidKey
+
+// ErrUnknownKey is returned when decrypting a value encrypted with an unknown key.
+var ErrUnknownKey = encryption.ErrUnknownKey
+
+// ErrDecrypt is returned for values that cannot be decrypted.
+var ErrDecrypt = encryption.ErrDecrypt
+
+// ErrNotSearchable is returned by lookups on encrypted fields not in deterministic mode.
+var ErrNotSearchable = encryption.ErrNotSearchable
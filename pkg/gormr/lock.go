@@ -0,0 +1,15 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/lock"
+
+// Lock is a distributed lock held through the database, returned by Client.Lock and Client.TryLock.
+type Lock = lock.Lock
+
+// ErrLockHeld is returned by Client.TryLock when another owner holds the lock.
+var ErrLockHeld = lock.ErrLockHeld
+
+// ErrLockLost is returned when renewing or unlocking a lock that was released or whose lease expired.
+var ErrLockLost = lock.ErrLockLost
+
+// ErrInvalidTTL is returned when a lock is requested with a ttl lower than or equal to 0.
+var ErrInvalidTTL = lock.ErrInvalidTTL
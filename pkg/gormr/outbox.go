@@ -0,0 +1,24 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/outbox"
+
+// Outbox appends events in the transaction of the data they describe, returned by Client.Outbox.
+type Outbox = outbox.Outbox
+
+// OutboxEvent is a message stored in the gormr_outbox table until it is published.
+type OutboxEvent = outbox.Event
+
+// Relay polls the outbox and publishes the pending events, created by Outbox.NewRelay.
+type Relay = outbox.Relay
+
+// RelayConfig configures a Relay: batch size, poll interval, retry backoff and error reporting.
+type RelayConfig = outbox.RelayConfig
+
+// Publisher delivers outbox events to a message broker.
+type Publisher = outbox.Publisher
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc = outbox.PublisherFunc
+
+// ErrEventNotDead is returned by Outbox.Requeue for events that are not dead-lettered.
+var ErrEventNotDead = outbox.ErrEventNotDead
@@ -0,0 +1,41 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/queue"
+
+// Queue is a named job queue returned by Client.Queue.
+type Queue = queue.Queue
+
+// QueueConfig configures a Queue: visibility timeout, attempts and retry backoff.
+type QueueConfig = queue.Config
+
+// Job is a unit of work stored in the gormr_jobs table.
+type Job = queue.Job
+
+// JobStatus is the state of a job.
+type JobStatus = queue.Status
+
+// JobHandler processes a job. Returning an error makes the queue retry or dead-letter the job.
+type JobHandler = queue.Handler
+
+// WorkerConfig configures Queue.Work.
+type WorkerConfig = queue.WorkerConfig
+
+// EnqueueOption customises a job added by Queue.Enqueue.
+type EnqueueOption = queue.EnqueueOption
+
+// Job statuses.
+const (
+	JobReady   = queue.StatusReady
+	JobRunning = queue.StatusRunning
+	JobDone    = queue.StatusDone
+	JobDead    = queue.StatusDead
+)
+
+// Delay makes the job available only after the given duration.
+var Delay = queue.Delay
+
+// MaxAttempts overrides the queue's number of attempts for the job.
+var MaxAttempts = queue.MaxAttempts
+
+// ErrJobLost is returned when finishing a job whose claim expired and was taken by another worker.
+var ErrJobLost = queue.ErrJobLost
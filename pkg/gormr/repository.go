@@ -0,0 +1,237 @@
+package gormr
+
+import (
+	"context"
+	"iter"
+
+	"github.com/alejandro-sotelo/gormr/internal/repository"
+)
+
+// Repository is the generic repository returned by Client.Repo.
+type Repository = repository.Repository
+
+// Spec describes which records a query targets.
+type Spec = repository.Spec
+
+// Aggregation describes an aggregate query over the records matching a Spec.
+type Aggregation = repository.Aggregation
+
+// PageRequest describes the page requested from Paginate.
+type PageRequest = repository.PageRequest
+
+// Page is a page of results together with its pagination metadata.
+type Page[T any] = repository.Page[T]
+
+// UpsertOptions configures how Repository.Upsert resolves conflicts.
+type UpsertOptions = repository.UpsertOptions
+
+// UpsertResult reports the outcome of Repository.Upsert.
+type UpsertResult = repository.UpsertResult
+
+// Versioned is implemented by models that opt into optimistic locking without a struct tag.
+type Versioned = repository.Versioned
+
+// QueryOption customises the query run by a find method.
+type QueryOption = repository.QueryOption
+
+// LockMode selects the row lock taken by a read.
+type LockMode = repository.LockMode
+
+// Row lock modes, see WithLock.
+const (
+	ForUpdate  = repository.ForUpdate
+	ForShare   = repository.ForShare
+	NoWait     = repository.NoWait
+	SkipLocked = repository.SkipLocked
+)
+
+// Operation is a Repository call passing through the middleware chain.
+type Operation = repository.Operation
+
+// Handler executes an Operation and returns the method result, if any, and its error.
+type Handler = repository.Handler
+
+// Middleware wraps the Handler executing operations, see Repository.Use.
+type Middleware = repository.Middleware
+
+// Operation names passed to middlewares.
+const (
+	OpCreate        = repository.OpCreate
+	OpCreateBatch   = repository.OpCreateBatch
+	OpUpsert        = repository.OpUpsert
+	OpUpdate        = repository.OpUpdate
+	OpUpdateFields  = repository.OpUpdateFields
+	OpUpdateMap     = repository.OpUpdateMap
+	OpUpdateWhere   = repository.OpUpdateWhere
+	OpDelete        = repository.OpDelete
+	OpDeleteByID    = repository.OpDeleteByID
+	OpDeleteWhere   = repository.OpDeleteWhere
+	OpSoftDelete    = repository.OpSoftDelete
+	OpRestore       = repository.OpRestore
+	OpForceDelete   = repository.OpForceDelete
+	OpPurge         = repository.OpPurge
+	OpGetByID       = repository.OpGetByID
+	OpGetAll        = repository.OpGetAll
+	OpGetPaginated  = repository.OpGetPaginated
+	OpGetByField    = repository.OpGetByField
+	OpFind          = repository.OpFind
+	OpCount         = repository.OpCount
+	OpAggregate     = repository.OpAggregate
+	OpPaginate      = repository.OpPaginate
+	OpIterate       = repository.OpIterate
+	OpFindInBatches = repository.OpFindInBatches
+)
+
+// ScanHook is implemented by GORM plugins transforming the rows streamed by Iterate.
+type ScanHook = repository.ScanHook
+
+// Propagation defines how a transaction relates to the one already active, if any.
+type Propagation = repository.Propagation
+
+// TxOption configures Repository.Transaction and Repository.RunInTransaction.
+type TxOption = repository.TxOption
+
+// UnitOfWork tracks new, dirty and removed entities and writes them in a single transaction.
+type UnitOfWork = repository.UnitOfWork
+
+// Tx is a manually controlled transaction started by Repository.Begin.
+type Tx = repository.Tx
+
+// Backoff returns how long to wait before a transaction retry.
+type Backoff = repository.Backoff
+
+// RetryError is returned when a transaction still fails with a retryable error after all retries.
+type RetryError = repository.RetryError
+
+// Transaction propagation modes.
+const (
+	PropagationRequired    = repository.PropagationRequired
+	PropagationRequiresNew = repository.PropagationRequiresNew
+	PropagationMandatory   = repository.PropagationMandatory
+	PropagationNever       = repository.PropagationNever
+	PropagationNested      = repository.PropagationNested
+)
+
+// NewSpec creates a Spec for the given model.
+var NewSpec = repository.NewSpec
+
+// WithTrashed includes soft-deleted records in the results.
+var WithTrashed = repository.WithTrashed
+
+// OnlyTrashed restricts the results to soft-deleted records.
+var OnlyTrashed = repository.OnlyTrashed
+
+// RawValues reads the values as stored, e.g. left encrypted.
+var RawValues = repository.RawValues
+
+// IsRawRead reports whether a query was given RawValues, for plugins transforming the values read.
+var IsRawRead = repository.IsRawRead
+
+// WithLock locks the rows read until the end of the transaction.
+var WithLock = repository.WithLock
+
+// Preload loads an association (e.g. "Orders.Lines") with a separate query per level.
+var Preload = repository.Preload
+
+// Joins loads a has-one or belongs-to association in the same query with a LEFT JOIN.
+var Joins = repository.Joins
+
+// InnerJoins is Joins with an INNER JOIN.
+var InnerJoins = repository.InnerJoins
+
+// NewAggregation creates an Aggregation over the records matching spec, run by Repository.Aggregate.
+var NewAggregation = repository.NewAggregation
+
+// Select restricts the columns read by a find.
+var Select = repository.Select
+
+// Omit leaves columns out of the columns read by a find.
+var Omit = repository.Omit
+
+// StrictDTO makes finds into a DTO fail with ErrUnmappedField on fields matching no column.
+var StrictDTO = repository.StrictDTO
+
+// WithActor returns a copy of ctx carrying the actor performing the operations run with it.
+var WithActor = repository.WithActor
+
+// ActorFromContext returns the actor carried by ctx, if any.
+var ActorFromContext = repository.ActorFromContext
+
+// WithPropagation sets how a transaction relates to the active one.
+var WithPropagation = repository.WithPropagation
+
+// WithIsolation sets the isolation level of a new transaction.
+var WithIsolation = repository.WithIsolation
+
+// ReadOnly starts a new transaction in read-only mode.
+var ReadOnly = repository.ReadOnly
+
+// WithRetry reruns a transaction failing with a retryable error up to maxRetries times.
+var WithRetry = repository.WithRetry
+
+// OnRetry registers a callback invoked before each transaction retry.
+var OnRetry = repository.OnRetry
+
+// WithLeakTimeout makes Repository.Begin log the creation stack of transactions unfinished after a timeout.
+var WithLeakTimeout = repository.WithLeakTimeout
+
+// ExponentialBackoff doubles base on every retry, up to maxDelay, with jitter.
+var ExponentialBackoff = repository.ExponentialBackoff
+
+// IsRetryable reports whether err is a serialization failure or deadlock worth retrying.
+var IsRetryable = repository.IsRetryable
+
+// ErrInvalidPage is returned by Paginate when the page or the page size is lower than 1.
+var ErrInvalidPage = repository.ErrInvalidPage
+
+// ErrInvalidBatchSize is returned by batch operations when the batch size is lower than 1.
+var ErrInvalidBatchSize = repository.ErrInvalidBatchSize
+
+// ErrNoChanges is returned by partial updates called without fields or changes.
+var ErrNoChanges = repository.ErrNoChanges
+
+// ErrStaleObject is returned when a versioned entity was changed or deleted concurrently.
+var ErrStaleObject = repository.ErrStaleObject
+
+// ErrSoftDeleteUnsupported is returned by soft delete operations on models without a gorm.DeletedAt field.
+var ErrSoftDeleteUnsupported = repository.ErrSoftDeleteUnsupported
+
+// ErrNotInTransaction is returned by operations that require a transaction when called outside one.
+var ErrNotInTransaction = repository.ErrNotInTransaction
+
+// ErrTransactionExists is returned by PropagationNever when a transaction is active.
+var ErrTransactionExists = repository.ErrTransactionExists
+
+// ErrLockOutsideTransaction is returned by reads using WithLock outside a transaction.
+var ErrLockOutsideTransaction = repository.ErrLockOutsideTransaction
+
+// ErrUnknownAssociation is returned for Preload or Joins paths naming no association of the model.
+var ErrUnknownAssociation = repository.ErrUnknownAssociation
+
+// ErrUnknownColumn is returned by aggregations naming a column the model does not have.
+var ErrUnknownColumn = repository.ErrUnknownColumn
+
+// ErrUnsupportedJoin is returned for Joins paths through has-many or many-to-many associations.
+var ErrUnsupportedJoin = repository.ErrUnsupportedJoin
+
+// ErrUnsupportedPreload is returned by Iterate given a Preload option.
+var ErrUnsupportedPreload = repository.ErrUnsupportedPreload
+
+// ErrUnmappedField is returned by finds given StrictDTO scanning into a DTO with a field matching
+// no model column.
+var ErrUnmappedField = repository.ErrUnmappedField
+
+// Paginate finds the page of records matching spec described by req.
+func Paginate[T any](ctx context.Context, r *Repository, spec *Spec, req PageRequest, opts ...QueryOption) (*Page[T], error) {
+	return repository.Paginate[T](ctx, r, spec, req, opts...)
+}
+
+// Iterate streams the records matching spec from a database cursor.
+func Iterate[T any](ctx context.Context, r *Repository, spec *Spec, opts ...QueryOption) iter.Seq2[T, error] {
+	return repository.Iterate[T](ctx, r, spec, opts...)
+}
+
+// FindInBatches loads the records matching spec in batches of batchSize and calls fn for each batch.
+func FindInBatches[T any](ctx context.Context, r *Repository, spec *Spec, batchSize int, fn func(batch []T) error, opts ...QueryOption) error {
+	return repository.FindInBatches(ctx, r, spec, batchSize, fn, opts...)
+}
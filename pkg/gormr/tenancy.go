@@ -0,0 +1,36 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/tenancy"
+
+// TenancyConfig configures Client.EnableTenancy.
+type TenancyConfig = tenancy.Config
+
+// TenancyStrategy selects how tenants are isolated.
+type TenancyStrategy = tenancy.Strategy
+
+// Tenancy strategies.
+const (
+	TenancyColumn = tenancy.StrategyColumn
+	TenancySchema = tenancy.StrategySchema
+)
+
+// WithTenant returns a copy of ctx carrying the tenant its statements are scoped to.
+var WithTenant = tenancy.WithTenant
+
+// TenantFromContext returns the tenant carried by ctx, if any.
+var TenantFromContext = tenancy.TenantFromContext
+
+// WithoutTenant returns a copy of ctx whose statements are not scoped to any tenant.
+var WithoutTenant = tenancy.WithoutTenant
+
+// ErrNoTenant is returned by statements on tenant-scoped models run without a tenant in context.
+var ErrNoTenant = tenancy.ErrNoTenant
+
+// ErrTenantMismatch is returned when writing an entity of another tenant.
+var ErrTenantMismatch = tenancy.ErrTenantMismatch
+
+// ErrInvalidTenant is returned by TenancySchema for tenants whose schema name is not an identifier.
+var ErrInvalidTenant = tenancy.ErrInvalidTenant
+
+// ErrTenantUpsert is returned by upserts on tenant-scoped models on MySQL and SQL Server.
+var ErrTenantUpsert = tenancy.ErrTenantUpsert
@@ -0,0 +1,21 @@
+package gormr
+
+import "github.com/alejandro-sotelo/gormr/internal/validation"
+
+// ValidationConfig configures Client.EnableValidation: tag name and custom rules.
+type ValidationConfig = validation.Config
+
+// ValidationRule is a custom rule usable in `validate` tags.
+type ValidationRule = validation.Rule
+
+// Validator is implemented by models checking invariants struct tags cannot express.
+type Validator = validation.Validator
+
+// ValidationError lists every violation found in the entities of a write.
+type ValidationError = validation.ValidationError
+
+// FieldError is a rule violated by a field.
+type FieldError = validation.FieldError
+
+// ErrValidation matches every ValidationError with errors.Is.
+var ErrValidation = validation.ErrValidation